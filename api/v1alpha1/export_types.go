@@ -30,6 +30,58 @@ const (
 	// ReconciledReasonError indicates an error was encountered while
 	// reconciling the CR
 	ReconciledReasonError status.ConditionReason = "ReconcileError"
+	// ConditionCompleted is a status condition type that indicates whether
+	// the export has finished successfully
+	ConditionCompleted status.ConditionType = "Completed"
+	// ConditionFailed is a status condition type that indicates the export
+	// job failed. The message holds the end of the export log
+	ConditionFailed status.ConditionType = "Failed"
+	// ConditionMergeConflict is a status condition type that indicates
+	// files were left unchanged as changes made to them in the repository
+	// conflict with the export
+	ConditionMergeConflict status.ConditionType = "MergeConflict"
+	// ConditionSecretsDetected is a status condition type that indicates
+	// possible credentials were found by secret scanning. The message
+	// lists the objects and fields they were found in
+	ConditionSecretsDetected status.ConditionType = "SecretsDetected"
+	// SecretsDetectedReasonFailed means the export failed as secrets were
+	// detected
+	SecretsDetectedReasonFailed status.ConditionReason = "ExportFailed"
+	// SecretsDetectedReasonRedacted means the secrets detected were
+	// redacted from the export
+	SecretsDetectedReasonRedacted status.ConditionReason = "Redacted"
+	// ConditionDrifted is a status condition type that indicates whether
+	// the cluster differs from the exported state in the repository
+	ConditionDrifted status.ConditionType = "Drifted"
+	// DriftedReasonDetected means objects in the cluster differ from the
+	// repository
+	DriftedReasonDetected status.ConditionReason = "DriftDetected"
+	// DriftedReasonInSync means the cluster matches the repository
+	DriftedReasonInSync status.ConditionReason = "InSync"
+)
+
+// ExportPhase is a label for the stage an export is in
+type ExportPhase string
+
+const (
+	// ExportPhasePending means the Export has been accepted but nothing
+	// has been created for it yet, or a scheduled export is waiting to run
+	ExportPhasePending ExportPhase = "Pending"
+	// ExportPhaseProvisioning means the resources the export job depends
+	// on are being created
+	ExportPhaseProvisioning ExportPhase = "Provisioning"
+	// ExportPhaseRunning means objects are being exported from the cluster
+	ExportPhaseRunning ExportPhase = "Running"
+	// ExportPhasePushing means the exported objects are being pushed to git
+	// or packaged for download
+	ExportPhasePushing ExportPhase = "Pushing"
+	// ExportPhaseSucceeded means the export completed successfully
+	ExportPhaseSucceeded ExportPhase = "Succeeded"
+	// ExportPhaseFailed means the export job failed
+	ExportPhaseFailed ExportPhase = "Failed"
+	// ExportPhaseWatching means a continuous export is watching the
+	// namespace and committing its changes
+	ExportPhaseWatching ExportPhase = "Watching"
 )
 
 type ExportSpec struct {
@@ -39,15 +91,270 @@ type ExportSpec struct {
 	// Branch within the git repository
 	Branch string `json:"branch,omitempty"`
 	// Git repository which will be cloned and updated
-	Repo   string `json:"repo,omitempty"`
+	Repo string `json:"repo,omitempty"`
+	// Directory within the repository the exported objects are written
+	// to, for example clusters/prod/team-a. Defaults to the name of the
+	// namespace. Exports sharing a repository should each use a path of
+	// their own
+	Path string `json:"path,omitempty"`
+	// MergeStrategy used when writing exported files over files that
+	// were changed in the repository. Overwrite replaces them, ThreeWay
+	// merges the changes with the export and reports conflicting files
+	// in the MergeConflict condition. Defaults to Overwrite
+	// +kubebuilder:validation:Enum=Overwrite;ThreeWay
+	MergeStrategy string `json:"mergeStrategy,omitempty"`
+	// Format the exported objects are written in. manifests writes a file
+	// per object, kustomize adds a kustomization.yaml listing every
+	// object and helm writes a chart with image tags, replica counts and
+	// Route hosts lifted into values.yaml. Defaults to manifests
+	// +kubebuilder:validation:Enum=manifests;kustomize;helm
+	Format string `json:"format,omitempty"`
+	// Generators replaces exported ConfigMaps and Secrets with
+	// configMapGenerator and secretGenerator entries backed by a file per
+	// key. Only supported by the kustomize format
+	Generators bool `json:"generators,omitempty"`
 	// Email used to specify the user who performed the git commit
-	Email  string `json:"email,omitempty"`
+	Email string `json:"email,omitempty"`
+	// Name used to specify the user who performed the git commit.
+	// Defaults to GitOps Primer
+	AuthorName string `json:"authorName,omitempty"`
+	// Go template for the subject of the commit. The namespace, the name
+	// of the Export, the user running the export and the time of the
+	// commit are available as .Namespace, .Name, .User and .Timestamp.
+	// The body of the commit lists the objects added, modified and deleted
+	CommitMessage string `json:"commitMessage,omitempty"`
 	// Predefined secret that contains an SSH key that will
 	// be used for git cloning and pushing
 	Secret string `json:"secret,omitempty"`
+	// Predefined secret that contains credentials used for git cloning
+	// and pushing over HTTPS. Either username and password (or token)
+	// keys, or a .git-credentials key in git-credential-store format
+	HTTPSSecret string `json:"httpsSecret,omitempty"`
+	// Name of the key within secret holding the SSH private key. Defaults
+	// to the first of ssh-privatekey, id_ed25519, id_ecdsa and id_rsa. A
+	// passphrase for the key may be stored in the passphrase key
+	SSHKeyName string `json:"sshKeyName,omitempty"`
+	// Entries in known_hosts format used to verify the git server. These
+	// may also be stored in the known_hosts key of secret. When neither is
+	// set the export fails unless insecureAcceptHostKeys is set
+	KnownHosts string `json:"knownHosts,omitempty"`
+	// InsecureAcceptHostKeys trusts whatever keys the git server presents
+	// when no known_hosts are given, leaving the export open to a server
+	// impersonating the git server
+	InsecureAcceptHostKeys bool `json:"insecureAcceptHostKeys,omitempty"`
+	// PullRequest pushes the export to a branch of its own and opens a
+	// pull request against branch rather than pushing to branch directly.
+	// Only supported by the git method
+	PullRequest *PullRequestSpec `json:"pullRequest,omitempty"`
+	// Signing signs every commit and tag made by the export. Only
+	// supported by the git method
+	Signing *SigningSpec `json:"signing,omitempty"`
+	// Encryption encrypts the data and stringData of exported Secrets with
+	// SOPS before they are committed. Only supported by the git method
+	Encryption *EncryptionSpec `json:"encryption,omitempty"`
+	// ExternalSecrets replaces exported Secrets with ExternalSecrets reading
+	// their values from a SecretStore, so that no secret values are
+	// exported. Only supported by the git method
+	ExternalSecrets *ExternalSecretsSpec `json:"externalSecrets,omitempty"`
+	// RedactSecrets replaces the values of exported Secrets with
+	// REDACTED:<key> placeholders and commits a SECRETS.md checklist of
+	// the values to fill in. Only supported by the git method
+	RedactSecrets bool `json:"redactSecrets,omitempty"`
+	// SecretScanning checks the export for credentials outside of Secrets
+	// before it is committed. Only supported by the git method
+	SecretScanning *SecretScanningSpec `json:"secretScanning,omitempty"`
+	// DryRun runs the export and works out the changes against branch
+	// without committing or pushing them. A summary is reported in the
+	// status and the unified diff can be downloaded. Only supported by
+	// the git method and not by scheduled exports
+	DryRun bool `json:"dryRun,omitempty"`
+	// DriftCheck runs the export on the schedule in compare-only mode,
+	// reporting how the cluster differs from branch without committing or
+	// pushing anything. Requires a schedule
+	DriftCheck bool `json:"driftCheck,omitempty"`
+	// Bootstrap generates the manifests pointing a GitOps tool at the
+	// export. Only supported by the git method
+	Bootstrap *BootstrapSpec `json:"bootstrap,omitempty"`
 	// Set automatically by the webhook to dictate who will
 	// run the export process
-	User   string `json:"user,omitempty"`
+	User string `json:"user,omitempty"`
+	// Schedule in Cron format. When set the export is run
+	// periodically rather than once. Only supported by the git method
+	Schedule string `json:"schedule,omitempty"`
+	// ConcurrencyPolicy specifies how to treat concurrent runs of a
+	// scheduled export. Defaults to Forbid
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty"`
+	// Number of successful scheduled export jobs to retain
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+	// Number of failed scheduled export jobs to retain
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+	// Continuous keeps the export running, watching the namespace and
+	// committing changes to branch shortly after they are made rather than
+	// exporting once. Only supported by the git method and not by
+	// scheduled exports
+	Continuous *ContinuousSpec `json:"continuous,omitempty"`
+	// Kinds to export in the form Kind.group, for example Deployment.apps
+	// or ConfigMap for the core group
+	IncludedKinds []string `json:"includedKinds,omitempty"`
+	// Kinds to leave out of the export in the form Kind.group. Takes
+	// precedence over every other selection field
+	ExcludedKinds []string `json:"excludedKinds,omitempty"`
+	// Only export objects with labels matching the selector
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// Objects to export by name. These are exported in addition to any
+	// objects selected by includedKinds and labelSelector
+	Objects []ObjectReference `json:"objects,omitempty"`
+	// Number of times a failed export is retried before the Export is
+	// marked as failed. Retries are delayed by an exponential back-off
+	// capped at six minutes. Defaults to 6
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// Number of seconds an export run, including retries, may take
+	// before it is marked as failed
+	// +kubebuilder:validation:Minimum=1
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+}
+
+// PullRequestSpec configures how pull requests are opened for an export
+type PullRequestSpec struct {
+	// Provider hosting the repository
+	// +kubebuilder:validation:Enum=github;gitlab;gitea
+	Provider string `json:"provider"`
+	// Predefined secret with a token key holding the API token used to
+	// open the pull request
+	TokenSecret string `json:"tokenSecret"`
+	// Base URL of the API of the provider. Defaults to the API of the
+	// host of repo, for example https://api.github.com
+	APIURL string `json:"apiURL,omitempty"`
+	// Repository to open the pull request in, owner/name or the project
+	// path on GitLab. Defaults to the path of repo
+	Repository string `json:"repository,omitempty"`
+}
+
+// SigningSpec configures the key commits are signed with
+type SigningSpec struct {
+	// Format of the signing key, a GPG private key or an SSH private key.
+	// SSH signing requires the git server to support SSH signatures
+	// +kubebuilder:validation:Enum=gpg;ssh
+	Format string `json:"format"`
+	// Predefined secret with the private signing key in the key key and,
+	// for a key protected by a passphrase, the passphrase key
+	Secret string `json:"secret"`
+}
+
+// EncryptionSpec configures how Secrets are encrypted. The sops provider
+// needs at least one recipient in ageRecipients or secret, the
+// sealedsecrets provider needs a certificate
+type EncryptionSpec struct {
+	// Provider encrypting the Secrets, sops encrypts their data in place
+	// and sealedsecrets replaces them with Bitnami SealedSecrets. Defaults
+	// to sops
+	// +kubebuilder:validation:Enum=sops;sealedsecrets
+	Provider string `json:"provider,omitempty"`
+	// Age recipients, public keys starting with age1, Secrets are
+	// encrypted for
+	AgeRecipients []string `json:"ageRecipients,omitempty"`
+	// Predefined secret listing further age recipients, one per line, in
+	// the recipients key. An age identity in the keys.txt key lets
+	// Secrets that have not changed keep their previous ciphertext
+	Secret string `json:"secret,omitempty"`
+	// Public certificate of the sealed-secrets controller the Secrets are
+	// sealed with
+	Certificate *CertificateReference `json:"certificate,omitempty"`
+}
+
+// CertificateReference locates a PEM encoded certificate in either a
+// ConfigMap or a Secret
+type CertificateReference struct {
+	// ConfigMap holding the certificate
+	ConfigMap string `json:"configMap,omitempty"`
+	// Secret holding the certificate
+	Secret string `json:"secret,omitempty"`
+	// Key holding the certificate. Defaults to cert.pem
+	Key string `json:"key,omitempty"`
+}
+
+// ContinuousSpec configures how a continuous export batches up changes
+type ContinuousSpec struct {
+	// How long the namespace has to go without changes before they are
+	// committed, as a duration. Defaults to 10s
+	Debounce string `json:"debounce,omitempty"`
+	// Longest time changes are held back while the namespace keeps
+	// changing, as a duration. Defaults to 1m
+	MaxDelay string `json:"maxDelay,omitempty"`
+}
+
+// ExternalSecretsSpec configures the ExternalSecrets replacing exported
+// Secrets
+type ExternalSecretsSpec struct {
+	// Name of the store the values are read from
+	SecretStore string `json:"secretStore"`
+	// Kind of the store. Defaults to SecretStore
+	// +kubebuilder:validation:Enum=SecretStore;ClusterSecretStore
+	SecretStoreKind string `json:"secretStoreKind,omitempty"`
+	// Go template of the key in the store, with .Namespace and .Name of
+	// the Secret and .Key within it. When .Key is not used every key of the
+	// Secret is read as a property of the same key in the store. Defaults
+	// to {{.Namespace}}/{{.Name}}
+	KeyTemplate string `json:"keyTemplate,omitempty"`
+	// How often the values are read from the store. Defaults to 1h
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+}
+
+// SecretScanningSpec configures how the export is checked for credentials
+type SecretScanningSpec struct {
+	// Policy Fail fails the export when possible credentials are found,
+	// Redact replaces them with REDACTED:<key> placeholders. Defaults to
+	// Fail
+	// +kubebuilder:validation:Enum=Fail;Redact
+	Policy string `json:"policy,omitempty"`
+	// Patterns matched in addition to the built-in patterns for private
+	// keys, JWTs, AWS keys and password assignments
+	Patterns []SecretPattern `json:"patterns,omitempty"`
+}
+
+// SecretPattern is a regular expression matching a credential. When the
+// expression has a capturing group only the group is redacted
+type SecretPattern struct {
+	// Name of the pattern reported with the objects it matched
+	Name string `json:"name"`
+	// Regular expression in Go syntax
+	Regex string `json:"regex"`
+}
+
+// BootstrapSpec configures the manifests pointing a GitOps tool at the
+// export
+type BootstrapSpec struct {
+	// Tool the manifests are generated for, argocd generates an
+	// Application and flux a GitRepository and a Kustomization
+	// +kubebuilder:validation:Enum=argocd;flux
+	Tool string `json:"tool"`
+	// Mode Commit writes the manifests to path in the repository, Apply
+	// creates them in the cluster as the user of the export once the
+	// export has been pushed. Defaults to Commit
+	// +kubebuilder:validation:Enum=Commit;Apply
+	Mode string `json:"mode,omitempty"`
+	// Path of the file within the repository the manifests are written to
+	// by the Commit mode. Defaults to bootstrap/<namespace>-<name>.yaml
+	Path string `json:"path,omitempty"`
+	// Namespace of the generated objects. Defaults to argocd for Argo CD
+	// and flux-system for Flux
+	Namespace string `json:"namespace,omitempty"`
+	// Project of the Argo CD Application. Defaults to default
+	Project string `json:"project,omitempty"`
+	// Secret in namespace Flux reads the credentials for repo from
+	SecretRef string `json:"secretRef,omitempty"`
+}
+
+// ObjectReference identifies an object within the namespace being exported
+type ObjectReference struct {
+	// API group of the object, empty for the core group
+	Group string `json:"group,omitempty"`
+	// Kind of the object
+	Kind string `json:"kind"`
+	// Name of the object
+	Name string `json:"name"`
 }
 
 // ExportStatus defines the observed state of Export
@@ -58,11 +365,95 @@ type ExportStatus struct {
 	Conditions status.Conditions `json:"conditions,omitempty"`
 	// Route that is defined by the controller to specify the
 	// location of the zip file
-	Route      string            `json:"route,omitempty"`
+	Route string `json:"route,omitempty"`
+	// Last time a scheduled export was started
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// Last time a scheduled export completed successfully
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Phase of the current or most recent export run
+	// +kubebuilder:validation:Enum=Pending;Provisioning;Running;Pushing;Succeeded;Failed;Watching
+	Phase ExportPhase `json:"phase,omitempty"`
+	// Time the current or most recent export run started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Time the current or most recent export run completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// URL of the pull request opened by the most recent export run
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+	// Number of the pull request opened by the most recent export run
+	PullRequestNumber int `json:"pullRequestNumber,omitempty"`
+	// Commit last pushed to the branch of the pull request. The next
+	// export only replaces the branch while it still points at it
+	PullRequestHead string `json:"pullRequestHead,omitempty"`
+	// Fingerprint of the key the commits of the most recent export run
+	// were signed with
+	SigningKeyFingerprint string `json:"signingKeyFingerprint,omitempty"`
+	// Changes a dry run would have committed
+	Diff *DiffSummary `json:"diff,omitempty"`
+	// Drift between the cluster and the repository found by the most
+	// recent drift check
+	Drift *DriftStatus `json:"drift,omitempty"`
+	// Generation of the Export the status was last written for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// DiffSummary summarises the changes a dry run would have committed. The
+// objects are listed as Kind/name, or by path for files that are not
+// manifests
+type DiffSummary struct {
+	// Objects that would be added
+	Added []string `json:"added,omitempty"`
+	// Objects that would be changed
+	Changed []string `json:"changed,omitempty"`
+	// Objects that would be removed
+	Removed []string `json:"removed,omitempty"`
+	// URL the unified diff can be downloaded from
+	URL string `json:"url,omitempty"`
+}
+
+// DriftState describes how an object differs from the repository
+type DriftState string
+
+const (
+	// DriftStateModified means the object differs from the repository
+	DriftStateModified DriftState = "Modified"
+	// DriftStateNotInRepository means the object is in the cluster but
+	// not in the repository
+	DriftStateNotInRepository DriftState = "NotInRepository"
+	// DriftStateNotInCluster means the object is in the repository but no
+	// longer in the cluster
+	DriftStateNotInCluster DriftState = "NotInCluster"
+)
+
+// DriftStatus reports the objects that differ between the cluster and the
+// repository
+type DriftStatus struct {
+	// Time the drift check completed
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// Number of objects that differ. Objects may list fewer of them
+	Count int `json:"count"`
+	// Objects that differ, listed as Kind/name, or by path for files that
+	// are not manifests
+	Objects []DriftedObject `json:"objects,omitempty"`
+}
+
+// DriftedObject is an object that differs between the cluster and the
+// repository
+type DriftedObject struct {
+	// Object as Kind/name, or the path of files that are not manifests
+	Object string `json:"object"`
+	// State of the object
+	// +kubebuilder:validation:Enum=Modified;NotInRepository;NotInCluster
+	State DriftState `json:"state"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Method",type=string,JSONPath=`.spec.method`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Started",type=date,JSONPath=`.status.startTime`
+//+kubebuilder:printcolumn:name="Completed",type=date,JSONPath=`.status.completionTime`
+//+kubebuilder:printcolumn:name="Pull Request",type=string,JSONPath=`.status.pullRequestURL`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Export is the Schema for the exports API
 type Export struct {
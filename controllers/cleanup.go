@@ -0,0 +1,137 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
+)
+
+// The ClusterRole and ClusterRoleBinding that let an export impersonate its
+// user are cluster scoped, so they cannot be owned by the Export and are not
+// garbage collected with it. They are labelled with the name and namespace
+// of their Export instead, which the finalizer and the sweeper use to find
+// them.
+
+// clusterObjectLabels returns the labels identifying the cluster scoped
+// objects of an Export
+func clusterObjectLabels(m *primerv1alpha1.Export) map[string]string {
+	return map[string]string{
+		exportLabel:          m.Name,
+		exportNamespaceLabel: m.Namespace,
+	}
+}
+
+// clusterObjectToExport maps a cluster scoped object back to its Export
+func clusterObjectToExport(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[exportLabel]
+	if !ok {
+		return nil
+	}
+	namespace, ok := obj.GetLabels()[exportNamespaceLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
+
+// deleteClusterObjects deletes the ClusterRoleBinding and ClusterRole of
+// the Export in namespace with name, ignoring any that are already gone
+func deleteClusterObjects(ctx context.Context, c client.Client, namespace, name string) error {
+	meta := metav1.ObjectMeta{Name: "primer-export-" + namespace + "-" + name}
+	if err := c.Delete(ctx, &rbacv1.ClusterRoleBinding{ObjectMeta: meta}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := c.Delete(ctx, &rbacv1.ClusterRole{ObjectMeta: meta}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	return nil
+}
+
+// ClusterObjectSweeper periodically deletes primer ClusterRoles and
+// ClusterRoleBindings whose Export no longer exists. These are left behind
+// when an Export is removed without its finalizer running, for example
+// when the finalizer is removed by hand or the operator is uninstalled
+// first.
+type ClusterObjectSweeper struct {
+	client.Client
+	Interval time.Duration
+}
+
+// Start runs the sweep every Interval until ctx is done
+func (s *ClusterObjectSweeper) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, s.sweep, s.Interval)
+	return nil
+}
+
+// NeedLeaderElection makes sure only one operator sweeps at a time
+func (s *ClusterObjectSweeper) NeedLeaderElection() bool {
+	return true
+}
+
+func (s *ClusterObjectSweeper) sweep(ctx context.Context) {
+	log := ctrllog.FromContext(ctx).WithName("sweeper")
+
+	clusterRoles := &rbacv1.ClusterRoleList{}
+	if err := s.List(ctx, clusterRoles, client.HasLabels{exportLabel, exportNamespaceLabel}); err != nil {
+		log.Error(err, "Failed to list ClusterRoles")
+		return
+	}
+	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+	if err := s.List(ctx, clusterRoleBindings, client.HasLabels{exportLabel, exportNamespaceLabel}); err != nil {
+		log.Error(err, "Failed to list ClusterRoleBindings")
+		return
+	}
+
+	exports := map[types.NamespacedName]bool{}
+	for i := range clusterRoles.Items {
+		for _, req := range clusterObjectToExport(&clusterRoles.Items[i]) {
+			exports[req.NamespacedName] = true
+		}
+	}
+	for i := range clusterRoleBindings.Items {
+		for _, req := range clusterObjectToExport(&clusterRoleBindings.Items[i]) {
+			exports[req.NamespacedName] = true
+		}
+	}
+
+	for export := range exports {
+		err := s.Get(ctx, export, &primerv1alpha1.Export{})
+		if err == nil {
+			continue
+		}
+		if !errors.IsNotFound(err) {
+			log.Error(err, "Failed to get Export", "Export.Namespace", export.Namespace, "Export.Name", export.Name)
+			continue
+		}
+		log.Info("Deleting orphaned cluster scoped Primer Resources", "Export.Namespace", export.Namespace, "Export.Name", export.Name)
+		if err := deleteClusterObjects(ctx, s.Client, export.Namespace, export.Name); err != nil {
+			log.Error(err, "Failed to delete orphaned cluster scoped Primer Resources", "Export.Namespace", export.Namespace, "Export.Name", export.Name)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
+)
+
+// clusterObjects returns the ClusterRole and ClusterRoleBinding created for
+// the Export namespace/name
+func clusterObjects(namespace, name string) []client.Object {
+	m := &primerv1alpha1.Export{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	meta := metav1.ObjectMeta{Name: "primer-export-" + namespace + "-" + name, Labels: clusterObjectLabels(m)}
+	return []client.Object{&rbacv1.ClusterRole{ObjectMeta: meta}, &rbacv1.ClusterRoleBinding{ObjectMeta: meta}}
+}
+
+// remaining returns the names of the ClusterRoles and ClusterRoleBindings
+// left in the cluster
+func remaining(t *testing.T, c client.Client) (roles, bindings []string) {
+	t.Helper()
+	roleList := &rbacv1.ClusterRoleList{}
+	bindingList := &rbacv1.ClusterRoleBindingList{}
+	if err := c.List(context.TODO(), roleList); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.List(context.TODO(), bindingList); err != nil {
+		t.Fatal(err)
+	}
+	roles, bindings = []string{}, []string{}
+	for _, role := range roleList.Items {
+		roles = append(roles, role.Name)
+	}
+	for _, binding := range bindingList.Items {
+		bindings = append(bindings, binding.Name)
+	}
+	sort.Strings(roles)
+	sort.Strings(bindings)
+	return roles, bindings
+}
+
+func TestSweep(t *testing.T) {
+	objs := []client.Object{
+		&primerv1alpha1.Export{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "kept"}},
+		// Not created by an export
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "admin"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "half-labelled", Labels: map[string]string{exportLabel: "gone"}}},
+	}
+	objs = append(objs, clusterObjects("demo", "kept")...)
+	objs = append(objs, clusterObjects("demo", "gone")...)
+	// An Export of the same name in another namespace does not keep them
+	objs = append(objs, clusterObjects("other", "kept")...)
+	// Only the binding was left behind
+	objs = append(objs, clusterObjects("demo", "binding-only")[1])
+	r := newTestReconciler(t, objs...)
+
+	(&ClusterObjectSweeper{Client: r.Client}).sweep(context.TODO())
+
+	roles, bindings := remaining(t, r.Client)
+	if want := []string{"admin", "half-labelled", "primer-export-demo-kept"}; !reflect.DeepEqual(roles, want) {
+		t.Errorf("ClusterRoles = %v, want %v", roles, want)
+	}
+	if want := []string{"primer-export-demo-kept"}; !reflect.DeepEqual(bindings, want) {
+		t.Errorf("ClusterRoleBindings = %v, want %v", bindings, want)
+	}
+}
+
+func TestDeleteClusterObjects(t *testing.T) {
+	r := newTestReconciler(t, clusterObjects("demo", "export")...)
+
+	// Deleting again, once the objects are gone, is not an error
+	for i := 0; i < 2; i++ {
+		if err := deleteClusterObjects(context.TODO(), r.Client, "demo", "export"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if roles, bindings := remaining(t, r.Client); len(roles) != 0 || len(bindings) != 0 {
+		t.Errorf("left %v and %v", roles, bindings)
+	}
+}
+
+func TestClusterObjectToExport(t *testing.T) {
+	m := &primerv1alpha1.Export{ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "export"}}
+	role := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Labels: clusterObjectLabels(m)}}
+	requests := clusterObjectToExport(role)
+	if len(requests) != 1 || requests[0].Namespace != "demo" || requests[0].Name != "export" {
+		t.Errorf("requests = %v", requests)
+	}
+
+	role.Labels = map[string]string{exportLabel: "export"}
+	if requests := clusterObjectToExport(role); len(requests) != 0 {
+		t.Errorf("requests for an object without a namespace label = %v", requests)
+	}
+}
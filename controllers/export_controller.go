@@ -18,8 +18,16 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	routev1 "github.com/openshift/api/route/v1"
@@ -33,14 +41,41 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
+	"github.com/cooktheryan/gitops-primer/export/pkg/commit"
+	"github.com/cooktheryan/gitops-primer/export/pkg/externalsecrets"
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+	"github.com/cooktheryan/gitops-primer/export/pkg/scan"
+)
+
+const (
+	// exportLabel is set on the Jobs and Pods of an Export to the name of the Export
+	exportLabel = "primer.gitops.io/export"
+	// exportNamespaceLabel is set on the cluster scoped objects of an Export
+	// to the namespace of the Export
+	exportNamespaceLabel = "primer.gitops.io/export-namespace"
+	// exportFinalizer lets the cluster scoped objects of an Export be
+	// deleted before the Export is removed
+	exportFinalizer = "primer.gitops.io/finalizer"
+	// templateHashAnnotation records the hash of the template an object
+	// running the export was built from, so that changes to the Export can
+	// be told apart from fields defaulted by the API server
+	templateHashAnnotation = "primer.gitops.io/template-hash"
 )
 
 // ExportReconciler reconciles a Export object
@@ -56,11 +91,13 @@ type ExportReconciler struct {
 //+kubebuilder:rbac:groups=primer.gitops.io,resources=exports/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=primer.gitops.io,resources=exports/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=users,verbs=impersonate
@@ -96,46 +133,71 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 		// Error reading the object - requeue the request.
 		log.Error(err, "Failed to get Export")
-		updateErrCondition(instance, err)
 		return ctrl.Result{}, err
 	}
 
-	// Check if the export job already exists, if not create a new one
-	// based on if its git or download the appropriate func will be called
-	found := &batchv1.Job{}
-	if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, found); err != nil {
-		if instance.Status.Completed {
-			return ctrl.Result{}, nil
-		}
-		if errors.IsNotFound(err) {
-			if instance.Spec.Method == "git" {
-				// Define a new job
-				job := r.jobGitForExport(instance)
-				log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
-				if err = r.Create(ctx, job); err != nil {
-					log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
-					updateErrCondition(instance, err)
-					return ctrl.Result{}, err
-				}
-				// Job created successfully - return and requeue
-				return ctrl.Result{Requeue: true}, nil
-			} else if instance.Spec.Method == "download" {
-				// Define a new job
-				job := r.jobDownloadForExport(instance)
-				log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
-				if err = r.Create(ctx, job); err != nil {
-					log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
-					updateErrCondition(instance, err)
-					return ctrl.Result{}, err
-				}
-				// Job created successfully - return and requeue
-				return ctrl.Result{Requeue: true}, nil
+	// Delete the cluster scoped objects, which are not garbage collected,
+	// when the Export is deleted
+	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(instance, exportFinalizer) {
+			log.Info("Cleaning up cluster scoped Primer Resources")
+			if err := deleteClusterObjects(ctx, r.Client, instance.Namespace, instance.Name); err != nil {
+				log.Error(err, "Failed to delete cluster scoped Primer Resources")
+				return ctrl.Result{}, err
+			}
+			deleteDriftMetrics(instance.Namespace, instance.Name)
+			controllerutil.RemoveFinalizer(instance, exportFinalizer)
+			if err := r.Update(ctx, instance); err != nil {
+				log.Error(err, "Failed to remove Export finalizer")
+				return ctrl.Result{}, err
 			}
 		}
-		log.Error(err, "Failed to get Job")
-		updateErrCondition(instance, err)
+		return ctrl.Result{}, nil
+	}
+	if !controllerutil.ContainsFinalizer(instance, exportFinalizer) {
+		controllerutil.AddFinalizer(instance, exportFinalizer)
+		if err := r.Update(ctx, instance); err != nil {
+			log.Error(err, "Failed to add Export finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Reject an Export that can never produce a working job
+	if err := validateExport(instance); err != nil {
+		log.Error(err, "Invalid Export")
+		r.updateErrCondition(ctx, instance, err)
+		return ctrl.Result{}, nil
+	}
+
+	// Reject an Export writing to the directory of another Export, each
+	// would prune the files of the other from the index they share
+	owner, err := r.sharedTarget(ctx, instance)
+	if err != nil {
+		log.Error(err, "Failed to list Exports")
 		return ctrl.Result{}, err
 	}
+	if owner != nil {
+		err := fmt.Errorf("%s is already exported to by Export %s/%s, set a path of its own", exportTarget(instance), owner.Namespace, owner.Name)
+		log.Error(err, "Invalid Export")
+		r.updateErrCondition(ctx, instance, err)
+		// Check again later in case the other Export is deleted
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	// Record that the Export has been seen before anything is created for it
+	if instance.Status.Phase == "" {
+		phase := primerv1alpha1.ExportPhasePending
+		if instance.Status.Completed {
+			phase = primerv1alpha1.ExportPhaseSucceeded
+		}
+		setPhase(instance, phase)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			log.Error(err, "Failed to update Export status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
 
 	// Check if the Service Account already exists, if not create a new one
 	foundSA := &corev1.ServiceAccount{}
@@ -147,17 +209,17 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new Service Account
 			serviceAcct := r.saGenerate(instance)
 			log.Info("Creating a new Service Account", "serviceAcct.Namespace", serviceAcct.Namespace, "serviceAcct.Name", serviceAcct.Name)
-			if err := r.Create(ctx, serviceAcct); err != nil {
+			if err := r.createForExport(ctx, instance, serviceAcct); err != nil {
 				log.Error(err, "Failed to create new Service Account", "serviceAcct.Namespace", serviceAcct.Namespace, "serviceAcct.Name", serviceAcct.Name)
 
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Service Account created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get Service Account")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
@@ -171,17 +233,17 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new Secret
 			proxySecret := r.secretGenerate(instance)
 			log.Info("Creating a new oauth Secret", "proxySecret.Namespace", proxySecret.Namespace, "proxySecret.Name", proxySecret.Name)
-			if err := r.Create(ctx, proxySecret); err != nil {
+			if err := r.createForExport(ctx, instance, proxySecret); err != nil {
 				log.Error(err, "Failed to create new oauth Secret", "proxySecret.Namespace", proxySecret.Namespace, "proxySecret.Name", proxySecret.Name)
 
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Secret created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get oauth Secret")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
@@ -195,17 +257,17 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new Route
 			appRoute := r.routeGenerate(instance)
 			log.Info("Creating a new Route", "appRoute.Namespace", appRoute.Namespace, "appRoute.Name", appRoute.Name)
-			if err := r.Create(ctx, appRoute); err != nil {
+			if err := r.createForExport(ctx, instance, appRoute); err != nil {
 				log.Error(err, "Failed to create new Route", "appRoute.Namespace", appRoute.Namespace, "appRoute.Name", appRoute.Name)
 
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Route created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get Route")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
@@ -219,16 +281,16 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new Role
 			clusterRole := r.clusterRoleGenerate(instance)
 			log.Info("Creating a new Cluster Role", "clusterRole.Namespace", clusterRole.Namespace, "clusterRole.Name", clusterRole.Name)
-			if err := r.Create(ctx, clusterRole); err != nil {
+			if err := r.createForExport(ctx, instance, clusterRole); err != nil {
 				log.Error(err, "Failed to create new Cluster Role", "clusterRole.Namespace", clusterRole.Namespace, "clusterRole.Name", clusterRole.Name)
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Cluster Role created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get Cluster Role")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
@@ -242,22 +304,22 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new Cluster Role Binding
 			clusterRoleBinding := r.clusterRoleBindingGenerate(instance)
 			log.Info("Creating a new Cluster Role Binding", "clusterRoleBinding.Namespace", clusterRoleBinding.Namespace, "clusterRoleBinding.Name", clusterRoleBinding.Name)
-			if err := r.Create(ctx, clusterRoleBinding); err != nil {
+			if err := r.createForExport(ctx, instance, clusterRoleBinding); err != nil {
 				log.Error(err, "Failed to create new Cluster Role Binding", "clusterRoleBinding.Namespace", clusterRoleBinding.Namespace, "clusterRoleBinding.Name", clusterRoleBinding.Name)
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Cluster Role Binding created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get Cluster Role Binding")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
-	// Check if method is download then check if network policy exists,
-	// if not create a new one
-	if instance.Spec.Method == "download" {
+	// Check if the export is downloaded then check if network policy
+	// exists, if not create a new one
+	if servesDownload(instance) {
 		foundNetPol := &networkingv1.NetworkPolicy{}
 		if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, foundNetPol); err != nil {
 			if instance.Status.Completed {
@@ -267,23 +329,27 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				// Define a new Network Policy
 				netPol := r.netPolGenerate(instance)
 				log.Info("Creating a new Network Policy", "netPol.Namespace", netPol.Namespace, "netPol.Name", netPol.Name)
-				if err := r.Create(ctx, netPol); err != nil {
+				if err := r.createForExport(ctx, instance, netPol); err != nil {
 					log.Error(err, "Failed to create new Network Policy", "netPol.Namespace", netPol.Namespace, "netPol.Name", netPol.Name)
-					updateErrCondition(instance, err)
+					r.updateErrCondition(ctx, instance, err)
 					return ctrl.Result{}, err
 				}
 				// NetPol  created successfully - return and requeue
 				return ctrl.Result{Requeue: true}, nil
 			}
 			log.Error(err, "Failed to get Network Policy")
-			updateErrCondition(instance, err)
+			r.updateErrCondition(ctx, instance, err)
 			return ctrl.Result{}, err
 		}
 	}
 
-	// Check if the PVC already exists, if not create a new one
+	// Check if the PVC already exists, if not create a new one.
+	// Scheduled and continuous exports use an emptyDir so that runs do
+	// not share state
 	foundVolume := &corev1.PersistentVolumeClaim{}
-	if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, foundVolume); err != nil {
+	if instance.Spec.Schedule != "" || instance.Spec.Continuous != nil {
+		log.V(1).Info("Skipping PVC for scheduled or continuous export")
+	} else if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, foundVolume); err != nil {
 		if instance.Status.Completed {
 			return ctrl.Result{}, nil
 		}
@@ -291,17 +357,17 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new PVC
 			persistentVC := r.pvcGenerate(instance)
 			log.Info("Creating a new PVC", "persistentVC.Namespace", persistentVC.Namespace, "persistentVC.Name", persistentVC.Name)
-			if err := r.Create(ctx, persistentVC); err != nil {
+			if err := r.createForExport(ctx, instance, persistentVC); err != nil {
 				log.Error(err, "Failed to create a PVC", "persistentVC.Namespace", persistentVC.Namespace, "persistentVC.Name", persistentVC.Name)
 
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Persistent Volume created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get PVC")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
@@ -319,25 +385,257 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			// Define a new service
 			service := r.svcGenerate(instance)
 			log.Info("Creating a new Service", "service.Namespace", service.Namespace, "service.Name", service.Name)
-			if err := r.Create(ctx, service); err != nil {
+			if err := r.createForExport(ctx, instance, service); err != nil {
 				log.Error(err, "Failed to create a Service", "service.Namespace", service.Namespace, "service.Name", service.Name)
 
-				updateErrCondition(instance, err)
+				r.updateErrCondition(ctx, instance, err)
 				return ctrl.Result{}, err
 			}
 			// Service created successfully - return and requeue
 			return ctrl.Result{Requeue: true}, nil
 		}
 		log.Error(err, "Failed to get Service")
-		updateErrCondition(instance, err)
+		r.updateErrCondition(ctx, instance, err)
+		return ctrl.Result{}, err
+	}
+
+	// Check if the export job already exists, if not create a new one
+	// based on if its git or download the appropriate func will be called.
+	// Scheduled exports are run by a CronJob rather than a single Job and
+	// continuous exports by a Deployment
+	found := &batchv1.Job{}
+	foundCronJob := &batchv1.CronJob{}
+	foundSync := &appsv1.Deployment{}
+	if instance.Spec.Continuous != nil {
+		if err := r.Get(ctx, types.NamespacedName{Name: "primer-sync-" + instance.Name, Namespace: instance.Namespace}, foundSync); err != nil {
+			if errors.IsNotFound(err) {
+				// Define a new Deployment
+				sync := r.syncDeploymentForExport(instance)
+				log.Info("Creating a new sync Deployment", "Deployment.Namespace", sync.Namespace, "Deployment.Name", sync.Name)
+				if err = r.createForExport(ctx, instance, sync); err != nil {
+					log.Error(err, "Failed to create new sync Deployment", "Deployment.Namespace", sync.Namespace, "Deployment.Name", sync.Name)
+					r.updateErrCondition(ctx, instance, err)
+					return ctrl.Result{}, err
+				}
+				// Deployment created successfully - return and requeue
+				return ctrl.Result{Requeue: true}, nil
+			}
+			log.Error(err, "Failed to get sync Deployment")
+			r.updateErrCondition(ctx, instance, err)
+			return ctrl.Result{}, err
+		}
+
+		// Keep the export in line with any changes made to the Export
+		if updateSyncDeployment(foundSync, r.syncDeploymentForExport(instance)) {
+			log.Info("Updating sync Deployment", "Deployment.Namespace", foundSync.Namespace, "Deployment.Name", foundSync.Name)
+			if err := r.Update(ctx, foundSync); err != nil {
+				log.Error(err, "Failed to update sync Deployment", "Deployment.Namespace", foundSync.Namespace, "Deployment.Name", foundSync.Name)
+				r.updateErrCondition(ctx, instance, err)
+				return ctrl.Result{}, err
+			}
+		}
+	} else if instance.Spec.Schedule != "" {
+		if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, foundCronJob); err != nil {
+			if errors.IsNotFound(err) {
+				// Define a new CronJob
+				cronJob := r.cronJobGitForExport(instance)
+				log.Info("Creating a new CronJob", "CronJob.Namespace", cronJob.Namespace, "CronJob.Name", cronJob.Name)
+				if err = r.createForExport(ctx, instance, cronJob); err != nil {
+					log.Error(err, "Failed to create new CronJob", "CronJob.Namespace", cronJob.Namespace, "CronJob.Name", cronJob.Name)
+					r.updateErrCondition(ctx, instance, err)
+					return ctrl.Result{}, err
+				}
+				// CronJob created successfully - return and requeue
+				return ctrl.Result{Requeue: true}, nil
+			}
+			log.Error(err, "Failed to get CronJob")
+			r.updateErrCondition(ctx, instance, err)
+			return ctrl.Result{}, err
+		}
+
+		// Keep the CronJob in line with any changes made to the Export
+		if updateCronJob(foundCronJob, r.cronJobGitForExport(instance)) {
+			log.Info("Updating CronJob", "CronJob.Namespace", foundCronJob.Namespace, "CronJob.Name", foundCronJob.Name)
+			if err := r.Update(ctx, foundCronJob); err != nil {
+				log.Error(err, "Failed to update CronJob", "CronJob.Namespace", foundCronJob.Namespace, "CronJob.Name", foundCronJob.Name)
+				r.updateErrCondition(ctx, instance, err)
+				return ctrl.Result{}, err
+			}
+		}
+	} else if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, found); err != nil {
+		if instance.Status.Completed {
+			return ctrl.Result{}, nil
+		}
+		if errors.IsNotFound(err) {
+			if instance.Spec.Method == "git" {
+				// Define a new job
+				job := r.jobGitForExport(instance)
+				log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+				if err = r.createForExport(ctx, instance, job); err != nil {
+					log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+					r.updateErrCondition(ctx, instance, err)
+					return ctrl.Result{}, err
+				}
+				// Job created successfully - return and requeue
+				return ctrl.Result{Requeue: true}, nil
+			} else if instance.Spec.Method == "download" {
+				// Define a new job
+				job := r.jobDownloadForExport(instance)
+				log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+				if err = r.createForExport(ctx, instance, job); err != nil {
+					log.Error(err, "Failed to create new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+					r.updateErrCondition(ctx, instance, err)
+					return ctrl.Result{}, err
+				}
+				// Job created successfully - return and requeue
+				return ctrl.Result{Requeue: true}, nil
+			}
+		}
+		log.Error(err, "Failed to get Job")
+		r.updateErrCondition(ctx, instance, err)
 		return ctrl.Result{}, err
 	}
 
+	if instance.Status.Conditions == nil {
+		instance.Status.Conditions = status.Conditions{}
+	}
+
+	// Continuous exports never complete, instead report whether the
+	// namespace is being watched
+	if instance.Spec.Continuous != nil {
+		phase := primerv1alpha1.ExportPhaseProvisioning
+		if isDeploymentReady(foundSync) {
+			phase = primerv1alpha1.ExportPhaseWatching
+		}
+		instance.Status.Conditions.SetCondition(
+			status.Condition{
+				Type:    primerv1alpha1.ConditionReconciled,
+				Status:  corev1.ConditionTrue,
+				Reason:  primerv1alpha1.ReconciledReasonComplete,
+				Message: "Reconcile complete",
+			})
+		setPhase(instance, phase)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			log.Error(err, "Failed to update Export status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Work out where the current export run is up to. Scheduled exports
+	// report on the most recent run started by the CronJob
+	job := found
+	if instance.Spec.Schedule != "" {
+		latest, err := r.latestJob(ctx, instance)
+		if err != nil {
+			log.Error(err, "Failed to list Jobs")
+			r.updateErrCondition(ctx, instance, err)
+			return ctrl.Result{}, err
+		}
+		job = latest
+	}
+	phase := primerv1alpha1.ExportPhasePending
+	if job != nil {
+		var err error
+		if phase, err = r.jobPhase(ctx, job); err != nil {
+			log.Error(err, "Failed to get export Pods")
+			r.updateErrCondition(ctx, instance, err)
+			return ctrl.Result{}, err
+		}
+		instance.Status.StartTime = job.Status.StartTime
+		instance.Status.CompletionTime = job.Status.CompletionTime
+	}
+
+	// Record what the export run reported back once it has succeeded.
+	// Possible secrets found by the scan end the run without failing the
+	// Job, so that it is not retried, and fail the export here instead
+	var res *result.Result
+	if phase == primerv1alpha1.ExportPhaseSucceeded {
+		var err error
+		if res, err = r.jobResult(ctx, job); err != nil {
+			log.Error(err, "Failed to read export result")
+			r.updateErrCondition(ctx, instance, err)
+			return ctrl.Result{}, err
+		}
+		if res != nil && len(res.SecretsDetected) != 0 {
+			phase = primerv1alpha1.ExportPhaseFailed
+		}
+	}
+
+	// Surface why the export failed, clearing out any earlier failure
+	// once a scheduled export runs again
+	if res != nil && len(res.SecretsDetected) != 0 {
+		log.Info("Export found possible secrets", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		instance.Status.Conditions.SetCondition(secretsFailedCondition())
+		setSecretsDetected(instance, primerv1alpha1.SecretsDetectedReasonFailed, res.SecretsDetected)
+	} else if phase == primerv1alpha1.ExportPhaseFailed {
+		failed, err := r.jobFailedCondition(ctx, job)
+		if err != nil {
+			log.Error(err, "Failed to get export Pods")
+			r.updateErrCondition(ctx, instance, err)
+			return ctrl.Result{}, err
+		}
+		log.Info("Export job failed", "Job.Namespace", job.Namespace, "Job.Name", job.Name, "Reason", failed.Reason)
+		instance.Status.Conditions.SetCondition(failed)
+		setSecretsDetected(instance, primerv1alpha1.SecretsDetectedReasonFailed, nil)
+	} else {
+		instance.Status.Conditions.RemoveCondition(primerv1alpha1.ConditionFailed)
+	}
+
+	if phase == primerv1alpha1.ExportPhaseSucceeded && res != nil {
+		instance.Status.PullRequestURL = res.PullRequestURL
+		instance.Status.PullRequestNumber = res.PullRequestNumber
+		// Runs without changes leave the branch where it was
+		if res.PullRequestHead != "" {
+			instance.Status.PullRequestHead = res.PullRequestHead
+		}
+		instance.Status.SigningKeyFingerprint = res.SigningKeyFingerprint
+		setMergeConflicts(instance, res.MergeConflicts)
+		setSecretsDetected(instance, primerv1alpha1.SecretsDetectedReasonRedacted, res.SecretsRedacted)
+		if res.Diff != nil && instance.Spec.DriftCheck {
+			setDrift(instance, res.Diff, job.Status.CompletionTime)
+		} else if res.Diff != nil {
+			instance.Status.Diff = &primerv1alpha1.DiffSummary{
+				Added:   withOmitted(res.Diff.Added, res.Diff.AddedCount),
+				Changed: withOmitted(res.Diff.Changed, res.Diff.ChangedCount),
+				Removed: withOmitted(res.Diff.Removed, res.Diff.RemovedCount),
+			}
+		}
+	}
+	if !instance.Spec.DriftCheck && instance.Status.Drift != nil {
+		instance.Status.Drift = nil
+		instance.Status.Conditions.RemoveCondition(primerv1alpha1.ConditionDrifted)
+		deleteDriftMetrics(instance.Namespace, instance.Name)
+	}
+
+	// Set reconcile status condition complete
+	instance.Status.Conditions.SetCondition(
+		status.Condition{
+			Type:    primerv1alpha1.ConditionReconciled,
+			Status:  corev1.ConditionTrue,
+			Reason:  primerv1alpha1.ReconciledReasonComplete,
+			Message: "Reconcile complete",
+		})
+
+	// Scheduled exports never complete, instead report when the
+	// export last ran and leave the resources in place for the next run
+	if instance.Spec.Schedule != "" {
+		instance.Status.LastScheduleTime = foundCronJob.Status.LastScheduleTime
+		instance.Status.LastSuccessfulTime = foundCronJob.Status.LastSuccessfulTime
+		setPhase(instance, phase)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			log.Error(err, "Failed to update Export status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// Check if deployment already exists, if not create one
 	// Deployment is created only for download to serve up
-	// the zip file that is created during export
+	// the zip file, or the diff of a dry run, created during export.
+	// Nothing is left to serve when the export found possible secrets
 	foundDeployment := &appsv1.Deployment{}
-	if instance.Spec.Method == "download" && isJobComplete(found) {
+	if servesDownload(instance) && phase == primerv1alpha1.ExportPhaseSucceeded {
 		log.Info("Serving up Export Download")
 		if err := r.Get(ctx, types.NamespacedName{Name: "primer-export-" + instance.Name, Namespace: instance.Namespace}, foundDeployment); err != nil {
 			if errors.IsNotFound(err) {
@@ -347,57 +645,52 @@ func (r *ExportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				if err := r.Create(ctx, deployment); err != nil {
 					log.Error(err, "Failed to create a Deployment", "deployment.Namespace", deployment.Namespace, "deployment.Name", deployment.Name)
 
-					updateErrCondition(instance, err)
+					r.updateErrCondition(ctx, instance, err)
 					return ctrl.Result{}, err
 				}
 				// Service created successfully - return and requeue
 				return ctrl.Result{Requeue: true}, nil
 			}
 			log.Error(err, "Failed to get Deployment")
-			updateErrCondition(instance, err)
+			r.updateErrCondition(ctx, instance, err)
 			return ctrl.Result{}, err
 		}
 	}
 
-	if instance.Status.Conditions == nil {
-		instance.Status.Conditions = status.Conditions{}
-	}
-
 	// Define the circumstances to set the Status Complete
 	// key value pair
-	if instance.Spec.Method != "download" {
+	if !servesDownload(instance) || phase == primerv1alpha1.ExportPhaseFailed {
 		instance.Status.Completed = isJobComplete(found)
-	} else if instance.Spec.Method == "download" && isDeploymentReady(foundDeployment) {
+	} else if isDeploymentReady(foundDeployment) {
 		instance.Status.Completed = isJobComplete(found)
+	} else if phase == primerv1alpha1.ExportPhaseSucceeded {
+		// The download is not available until it is being served
+		phase = primerv1alpha1.ExportPhasePushing
 	}
+	setPhase(instance, phase)
 
 	// Defines the address to access the exported zip file
 	instance.Status.Route = "https://" + defineRoute(foundRoute) + "/" + instance.Namespace + "-" + instance.ObjectMeta.CreationTimestamp.Rfc3339Copy().Format(time.RFC3339) + ".zip"
+	if instance.Spec.DryRun && instance.Status.Diff != nil {
+		instance.Status.Diff.URL = strings.TrimSuffix(instance.Status.Route, ".zip") + ".diff"
+	}
+	if err := r.Status().Update(ctx, instance); err != nil {
+		log.Error(err, "Failed to update Export status")
+		return ctrl.Result{}, err
+	}
 	if instance.Status.Completed {
 		log.Info("Job completed")
 		log.Info("Cleaning up Primer Resources")
-		if err := r.Status().Update(ctx, instance); err != nil {
-			log.Error(err, "Failed to update Export status")
-			updateErrCondition(instance, err)
-			return ctrl.Result{}, err
-		}
 		r.Delete(ctx, found, client.PropagationPolicy(metav1.DeletePropagationBackground))
 		r.Delete(ctx, foundClusterRole)
 		r.Delete(ctx, foundClusterRoleBinding)
-
-		// Set reconcile status condition complete
-		instance.Status.Conditions.SetCondition(
-			status.Condition{
-				Type:    primerv1alpha1.ConditionReconciled,
-				Status:  corev1.ConditionTrue,
-				Reason:  primerv1alpha1.ReconciledReasonComplete,
-				Message: "Reconcile complete",
-			})
 	}
 	return ctrl.Result{}, nil
 }
 
-func updateErrCondition(instance *primerv1alpha1.Export, err error) {
+// updateErrCondition records err on the Export and writes it back to the
+// cluster so that the error is visible to the user
+func (r *ExportReconciler) updateErrCondition(ctx context.Context, instance *primerv1alpha1.Export, err error) {
 	instance.Status.Conditions.SetCondition(
 		status.Condition{
 			Type:    primerv1alpha1.ConditionReconciled,
@@ -405,53 +698,676 @@ func updateErrCondition(instance *primerv1alpha1.Export, err error) {
 			Reason:  primerv1alpha1.ReconciledReasonError,
 			Message: err.Error(),
 		})
+	instance.Status.ObservedGeneration = instance.Generation
+	if err := r.Status().Update(ctx, instance); err != nil {
+		ctrllog.FromContext(ctx).Error(err, "Failed to update Export status")
+	}
+}
+
+// phaseMessages describes each phase in the Completed condition
+var phaseMessages = map[primerv1alpha1.ExportPhase]string{
+	primerv1alpha1.ExportPhasePending:      "Waiting for the export to run",
+	primerv1alpha1.ExportPhaseProvisioning: "Creating the resources needed by the export",
+	primerv1alpha1.ExportPhaseRunning:      "Exporting objects from the namespace",
+	primerv1alpha1.ExportPhasePushing:      "Publishing the exported objects",
+	primerv1alpha1.ExportPhaseSucceeded:    "Export completed",
+	primerv1alpha1.ExportPhaseFailed:       "Export failed",
+	primerv1alpha1.ExportPhaseWatching:     "Watching the namespace for changes",
+}
+
+// setPhase moves the Export to phase and records the transition in the
+// Completed condition. The caller is responsible for updating the status
+func setPhase(instance *primerv1alpha1.Export, phase primerv1alpha1.ExportPhase) {
+	instance.Status.Phase = phase
+	instance.Status.ObservedGeneration = instance.Generation
+	completed := status.Condition{
+		Type:    primerv1alpha1.ConditionCompleted,
+		Status:  corev1.ConditionFalse,
+		Reason:  status.ConditionReason(phase),
+		Message: phaseMessages[phase],
+	}
+	if phase == primerv1alpha1.ExportPhaseSucceeded {
+		completed.Status = corev1.ConditionTrue
+	}
+	instance.Status.Conditions.SetCondition(completed)
+}
+
+// setMergeConflicts reports the files an export could not merge in the
+// MergeConflict condition
+func setMergeConflicts(instance *primerv1alpha1.Export, conflicts []string) {
+	if len(conflicts) == 0 {
+		instance.Status.Conditions.RemoveCondition(primerv1alpha1.ConditionMergeConflict)
+		return
+	}
+	instance.Status.Conditions.SetCondition(
+		status.Condition{
+			Type:    primerv1alpha1.ConditionMergeConflict,
+			Status:  corev1.ConditionTrue,
+			Reason:  status.ConditionReason(primerv1alpha1.ConditionMergeConflict),
+			Message: "Changes in the repository conflict with the export, left unchanged: " + strings.Join(conflicts, ", "),
+		})
+}
+
+// setSecretsDetected reports the possible credentials found by secret
+// scanning in the SecretsDetected condition
+func setSecretsDetected(instance *primerv1alpha1.Export, reason status.ConditionReason, findings []string) {
+	if len(findings) == 0 {
+		instance.Status.Conditions.RemoveCondition(primerv1alpha1.ConditionSecretsDetected)
+		return
+	}
+	message := "Possible secrets found in: "
+	if reason == primerv1alpha1.SecretsDetectedReasonRedacted {
+		message = "Possible secrets redacted from: "
+	}
+	instance.Status.Conditions.SetCondition(
+		status.Condition{
+			Type:    primerv1alpha1.ConditionSecretsDetected,
+			Status:  corev1.ConditionTrue,
+			Reason:  reason,
+			Message: message + strings.Join(findings, ", "),
+		})
+}
+
+// withOmitted notes how many of count objects were left out of objects
+func withOmitted(objects []string, count int) []string {
+	if count <= len(objects) {
+		return objects
+	}
+	return append(objects, fmt.Sprintf("and %d more", count-len(objects)))
+}
+
+// setDrift reports the changes found by a drift check in the Drifted
+// condition, the drift status and the drift metrics
+func setDrift(instance *primerv1alpha1.Export, diff *result.Diff, checked *metav1.Time) {
+	drift := &primerv1alpha1.DriftStatus{LastCheckTime: checked}
+	counts := map[primerv1alpha1.DriftState]int{
+		primerv1alpha1.DriftStateNotInRepository: diff.AddedCount,
+		primerv1alpha1.DriftStateModified:        diff.ChangedCount,
+		primerv1alpha1.DriftStateNotInCluster:    diff.RemovedCount,
+	}
+	for state, objects := range map[primerv1alpha1.DriftState][]string{
+		primerv1alpha1.DriftStateNotInRepository: diff.Added,
+		primerv1alpha1.DriftStateModified:        diff.Changed,
+		primerv1alpha1.DriftStateNotInCluster:    diff.Removed,
+	} {
+		if counts[state] < len(objects) {
+			counts[state] = len(objects)
+		}
+		for _, object := range objects {
+			drift.Objects = append(drift.Objects, primerv1alpha1.DriftedObject{Object: object, State: state})
+		}
+	}
+	sort.Slice(drift.Objects, func(i, j int) bool {
+		return drift.Objects[i].Object < drift.Objects[j].Object
+	})
+	for _, count := range counts {
+		drift.Count += count
+	}
+	instance.Status.Drift = drift
+
+	drifted := status.Condition{
+		Type:    primerv1alpha1.ConditionDrifted,
+		Status:  corev1.ConditionFalse,
+		Reason:  primerv1alpha1.DriftedReasonInSync,
+		Message: "The cluster matches " + instance.Spec.Branch,
+	}
+	if drift.Count != 0 {
+		drifted.Status = corev1.ConditionTrue
+		drifted.Reason = primerv1alpha1.DriftedReasonDetected
+		drifted.Message = fmt.Sprintf("%d objects differ from %s", drift.Count, instance.Spec.Branch)
+	}
+	instance.Status.Conditions.SetCondition(drifted)
+	setDriftMetrics(instance, counts)
+}
+
+// createForExport creates obj for the Export, moving the Export into the
+// Provisioning phase first
+func (r *ExportReconciler) createForExport(ctx context.Context, instance *primerv1alpha1.Export, obj client.Object) error {
+	if instance.Status.Phase != primerv1alpha1.ExportPhaseProvisioning {
+		setPhase(instance, primerv1alpha1.ExportPhaseProvisioning)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return err
+		}
+	}
+	return r.Create(ctx, obj)
+}
+
+// latestJob returns the most recently created export Job for the Export
+// or nil if the Export has not run yet
+func (r *ExportReconciler) latestJob(ctx context.Context, instance *primerv1alpha1.Export) (*batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(instance.Namespace), client.MatchingLabels{exportLabel: instance.Name}); err != nil {
+		return nil, err
+	}
+	var latest *batchv1.Job
+	for i := range jobs.Items {
+		if latest == nil || latest.CreationTimestamp.Before(&jobs.Items[i].CreationTimestamp) {
+			latest = &jobs.Items[i]
+		}
+	}
+	return latest, nil
+}
+
+// jobPhase works out the phase of an export run from its Job and the
+// containers of its Pods. Objects are exported by the export init
+// container and published by the push container
+func (r *ExportReconciler) jobPhase(ctx context.Context, job *batchv1.Job) (primerv1alpha1.ExportPhase, error) {
+	if isJobComplete(job) {
+		return primerv1alpha1.ExportPhaseSucceeded, nil
+	}
+	if isJobFailed(job) {
+		return primerv1alpha1.ExportPhaseFailed, nil
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.Name == "push" && container.State.Running != nil {
+				return primerv1alpha1.ExportPhasePushing, nil
+			}
+		}
+	}
+	return primerv1alpha1.ExportPhaseRunning, nil
+}
+
+// jobFailedCondition returns the Failed condition for a failed export Job.
+// The export containers fall back to their log for the termination message
+// so the message ends with the log of the container that failed last
+func (r *ExportReconciler) jobFailedCondition(ctx context.Context, job *batchv1.Job) (status.Condition, error) {
+	failed := status.Condition{
+		Type:    primerv1alpha1.ConditionFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  status.ConditionReason(batchv1.JobFailed),
+		Message: "Export job failed",
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			failed.Reason = status.ConditionReason(condition.Reason)
+			failed.Message = condition.Message
+		}
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return failed, err
+	}
+	var name string
+	var last *corev1.ContainerStateTerminated
+	for _, pod := range pods.Items {
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		for _, container := range append(statuses, pod.Status.ContainerStatuses...) {
+			terminated := container.State.Terminated
+			if terminated == nil || terminated.ExitCode == 0 {
+				continue
+			}
+			if last == nil || last.FinishedAt.Before(&terminated.FinishedAt) {
+				name, last = container.Name, terminated
+			}
+		}
+	}
+	if last == nil {
+		return failed, nil
+	}
+	failed.Message = fmt.Sprintf("%s: %s container exited with code %d: %s", failed.Message, name, last.ExitCode, logExcerpt(last.Message))
+	return failed, nil
+}
+
+// secretsFailedCondition returns the Failed condition for an export run
+// that found possible secrets
+func secretsFailedCondition() status.Condition {
+	return status.Condition{
+		Type:    primerv1alpha1.ConditionFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  status.ConditionReason(primerv1alpha1.ConditionSecretsDetected),
+		Message: fmt.Sprintf("Export found possible secrets and was not committed, see the %s condition", primerv1alpha1.ConditionSecretsDetected),
+	}
+}
+
+// jobResult returns the Result written by the push container of a
+// succeeded export Job, or nil if its Pod is no longer around or the
+// message could not be read, such as when it was cut off
+func (r *ExportReconciler) jobResult(ctx context.Context, job *batchv1.Job) (*result.Result, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Status.ContainerStatuses {
+			terminated := container.State.Terminated
+			if container.Name == "push" && terminated != nil && terminated.ExitCode == 0 {
+				res, err := result.Parse(terminated.Message)
+				if err != nil {
+					ctrllog.FromContext(ctx).Error(err, "Ignoring unreadable export result", "job", job.Name)
+					return nil, nil
+				}
+				return res, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// logExcerpt returns the last lines of a container log
+func logExcerpt(log string) string {
+	lines := strings.Split(strings.TrimSpace(log), "\n")
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// podToExport maps the Pods of an export Job back to the Export so that
+// the phase follows the containers of the Pod
+func podToExport(obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[exportLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: obj.GetNamespace()}}}
+}
+
+// validateExport checks the Export for settings that are not supported
+func validateExport(m *primerv1alpha1.Export) error {
+	// Scheduled exports are only supported when pushing to git as
+	// each run of the download method would overwrite the last
+	if m.Spec.Schedule != "" && m.Spec.Method != "git" {
+		return fmt.Errorf("schedule is not supported by the %q method", m.Spec.Method)
+	}
+	if m.Spec.Secret != "" && m.Spec.HTTPSSecret != "" {
+		return fmt.Errorf("only one of secret and httpsSecret may be set")
+	}
+	if m.Spec.HTTPSSecret != "" && !strings.HasPrefix(m.Spec.Repo, "https://") {
+		return fmt.Errorf("httpsSecret requires an https:// repo, got %q", m.Spec.Repo)
+	}
+	if m.Spec.PullRequest != nil && m.Spec.Method != "git" {
+		return fmt.Errorf("pullRequest is not supported by the %q method", m.Spec.Method)
+	}
+	if m.Spec.Signing != nil && m.Spec.Method != "git" {
+		return fmt.Errorf("signing is not supported by the %q method", m.Spec.Method)
+	}
+	if _, err := commit.Message(m.Spec.CommitMessage, commit.Vars{}, nil); err != nil {
+		return fmt.Errorf("invalid commitMessage: %w", err)
+	}
+	if m.Spec.Path != "" {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("path is not supported by the %q method", m.Spec.Method)
+		}
+		if err := validatePath(m.Spec.Path); err != nil {
+			return err
+		}
+	}
+	if m.Spec.MergeStrategy != "" && m.Spec.Method != "git" {
+		return fmt.Errorf("mergeStrategy is not supported by the %q method", m.Spec.Method)
+	}
+	if m.Spec.Generators && m.Spec.Format != "kustomize" {
+		return fmt.Errorf("generators are only supported by the kustomize format")
+	}
+	if m.Spec.Encryption != nil {
+		if err := validateEncryption(m); err != nil {
+			return err
+		}
+	}
+	if x := m.Spec.ExternalSecrets; x != nil {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("externalSecrets is not supported by the %q method", m.Spec.Method)
+		}
+		if m.Spec.Encryption != nil {
+			return fmt.Errorf("only one of encryption and externalSecrets may be set")
+		}
+		if _, err := externalsecrets.ParseKeyTemplate(x.KeyTemplate); err != nil {
+			return fmt.Errorf("invalid externalSecrets keyTemplate: %w", err)
+		}
+		if x.RefreshInterval != "" {
+			if _, err := time.ParseDuration(x.RefreshInterval); err != nil {
+				return fmt.Errorf("invalid externalSecrets refreshInterval: %w", err)
+			}
+		}
+	}
+	if m.Spec.RedactSecrets {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("redactSecrets is not supported by the %q method", m.Spec.Method)
+		}
+		if m.Spec.Encryption != nil || m.Spec.ExternalSecrets != nil {
+			return fmt.Errorf("redactSecrets cannot be combined with encryption or externalSecrets")
+		}
+	}
+	if sc := m.Spec.SecretScanning; sc != nil {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("secretScanning is not supported by the %q method", m.Spec.Method)
+		}
+		patterns := []scan.Pattern{}
+		for _, p := range sc.Patterns {
+			patterns = append(patterns, scan.Pattern{Name: p.Name, Regex: p.Regex})
+		}
+		if _, err := scan.New(patterns); err != nil {
+			return fmt.Errorf("invalid secretScanning patterns: %w", err)
+		}
+	}
+	if m.Spec.DryRun {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("dryRun is not supported by the %q method", m.Spec.Method)
+		}
+		if m.Spec.Schedule != "" {
+			return fmt.Errorf("dryRun is not supported by scheduled exports")
+		}
+	}
+	if c := m.Spec.Continuous; c != nil {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("continuous is not supported by the %q method", m.Spec.Method)
+		}
+		if m.Spec.Schedule != "" {
+			return fmt.Errorf("continuous cannot be combined with schedule")
+		}
+		if m.Spec.DryRun || m.Spec.PullRequest != nil {
+			return fmt.Errorf("continuous cannot be combined with dryRun or pullRequest")
+		}
+		if _, _, err := continuousDelays(c); err != nil {
+			return err
+		}
+	}
+	if m.Spec.DriftCheck {
+		// The schedule check above limits drift checks to the git method
+		if m.Spec.Schedule == "" {
+			return fmt.Errorf("driftCheck requires a schedule")
+		}
+		if m.Spec.PullRequest != nil {
+			return fmt.Errorf("driftCheck cannot be combined with pullRequest")
+		}
+	}
+	if b := m.Spec.Bootstrap; b != nil {
+		if m.Spec.Method != "git" {
+			return fmt.Errorf("bootstrap is not supported by the %q method", m.Spec.Method)
+		}
+		if b.Tool == "flux" && m.Spec.Format == "helm" {
+			return fmt.Errorf("bootstrap with flux is not supported by the helm format")
+		}
+		if b.Path != "" {
+			if b.Mode == "Apply" {
+				return fmt.Errorf("bootstrap path is not supported by the Apply mode")
+			}
+			if err := validatePath(b.Path); err != nil {
+				return err
+			}
+		}
+		if b.Project != "" && b.Tool != "argocd" {
+			return fmt.Errorf("bootstrap project is only supported by argocd")
+		}
+		if b.SecretRef != "" && b.Tool != "flux" {
+			return fmt.Errorf("bootstrap secretRef is only supported by flux")
+		}
+	}
+	if m.Spec.LabelSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(m.Spec.LabelSelector); err != nil {
+			return fmt.Errorf("invalid labelSelector: %w", err)
+		}
+	}
+	for _, o := range m.Spec.Objects {
+		if o.Kind == "" || o.Name == "" {
+			return fmt.Errorf("objects must define both kind and name")
+		}
+	}
+	return nil
+}
+
+// validateEncryption checks the encryption of an Export against its
+// provider
+func validateEncryption(m *primerv1alpha1.Export) error {
+	e := m.Spec.Encryption
+	if m.Spec.Method != "git" {
+		return fmt.Errorf("encryption is not supported by the %q method", m.Spec.Method)
+	}
+	if encryptionProvider(e) == "sealedsecrets" {
+		if len(e.AgeRecipients) != 0 || e.Secret != "" {
+			return fmt.Errorf("ageRecipients and secret are not supported by the sealedsecrets provider")
+		}
+		if e.Certificate == nil || (e.Certificate.ConfigMap == "") == (e.Certificate.Secret == "") {
+			return fmt.Errorf("the sealedsecrets provider requires a certificate in either a configMap or a secret")
+		}
+		return nil
+	}
+	if e.Certificate != nil {
+		return fmt.Errorf("certificate is only supported by the sealedsecrets provider")
+	}
+	if len(e.AgeRecipients) == 0 && e.Secret == "" {
+		return fmt.Errorf("encryption requires ageRecipients or secret")
+	}
+	for _, r := range e.AgeRecipients {
+		if !strings.HasPrefix(r, "age1") {
+			return fmt.Errorf("invalid age recipient %q", r)
+		}
+	}
+	if m.Spec.Generators {
+		return fmt.Errorf("encryption is not supported with generators")
+	}
+	if m.Spec.Format == "helm" {
+		return fmt.Errorf("encryption is not supported by the helm format")
+	}
+	return nil
+}
+
+// encryptionProvider returns the provider encrypting Secrets
+func encryptionProvider(e *primerv1alpha1.EncryptionSpec) string {
+	if e.Provider == "" {
+		return "sops"
+	}
+	return e.Provider
+}
+
+// validatePath makes sure the export can only write within the repository
+// and not into the git directory
+func validatePath(p string) error {
+	clean := path.Clean(p)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("path %q must be within the repository", p)
+	}
+	if clean == ".git" || strings.HasPrefix(clean, ".git/") {
+		return fmt.Errorf("path %q must not be within the .git directory", p)
+	}
+	return nil
+}
+
+// exportTarget returns the repository, branch and directory an Export
+// writes to
+func exportTarget(m *primerv1alpha1.Export) string {
+	p := m.Spec.Path
+	if p == "" {
+		p = m.Namespace
+	}
+	return fmt.Sprintf("path %q of branch %q of %s", path.Clean(p), m.Spec.Branch, strings.TrimSuffix(m.Spec.Repo, ".git"))
+}
+
+// holdsTarget reports whether an Export still writes to its directory. A
+// one off export that has completed no longer does, so its directory can
+// be taken over by a new Export
+func holdsTarget(m *primerv1alpha1.Export) bool {
+	if m.Spec.Method != "git" || m.Spec.DryRun || !m.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return !m.Status.Completed || m.Spec.Schedule != "" || m.Spec.Continuous != nil
+}
+
+// sharedTarget returns the Export that writes to the same directory of the
+// same repository and branch as m. The oldest Export keeps the directory,
+// so nil is returned when m is older than all the others
+func (r *ExportReconciler) sharedTarget(ctx context.Context, m *primerv1alpha1.Export) (*primerv1alpha1.Export, error) {
+	if !holdsTarget(m) {
+		return nil, nil
+	}
+	exports := &primerv1alpha1.ExportList{}
+	if err := r.List(ctx, exports); err != nil {
+		return nil, err
+	}
+	var owner *primerv1alpha1.Export
+	for i := range exports.Items {
+		other := &exports.Items[i]
+		if other.Namespace == m.Namespace && other.Name == m.Name {
+			continue
+		}
+		if !holdsTarget(other) || exportTarget(other) != exportTarget(m) || !olderExport(other, m) {
+			continue
+		}
+		if owner == nil || olderExport(other, owner) {
+			owner = other
+		}
+	}
+	return owner, nil
+}
+
+// olderExport reports whether a was created before b, Exports created in
+// the same second are ordered by namespace and name
+func olderExport(a, b *primerv1alpha1.Export) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// outputEnv returns the environment deciding the format the export is
+// written in
+func outputEnv(m *primerv1alpha1.Export) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{Name: "FORMAT", Value: m.Spec.Format},
+		{Name: "GENERATORS", Value: strconv.FormatBool(m.Spec.Generators)},
+	}
+}
+
+// selectionEnv returns the environment used by the ResourceSelectionPlugin
+// to decide which objects are exported
+func selectionEnv(m *primerv1alpha1.Export) []corev1.EnvVar {
+	var selector string
+	if m.Spec.LabelSelector != nil {
+		// The selector has already been checked by validateExport
+		s, _ := metav1.LabelSelectorAsSelector(m.Spec.LabelSelector)
+		selector = s.String()
+	}
+	objects := []string{}
+	for _, o := range m.Spec.Objects {
+		gk := schema.GroupKind{Group: o.Group, Kind: o.Kind}
+		objects = append(objects, gk.String()+"/"+o.Name)
+	}
+	return []corev1.EnvVar{
+		{Name: "INCLUDED_KINDS", Value: strings.Join(m.Spec.IncludedKinds, ",")},
+		{Name: "EXCLUDED_KINDS", Value: strings.Join(m.Spec.ExcludedKinds, ",")},
+		{Name: "LABEL_SELECTOR", Value: selector},
+		{Name: "OBJECTS", Value: strings.Join(objects, ",")},
+	}
 }
 
 // jobGitForExport returns a instance Job object
 func (r *ExportReconciler) jobGitForExport(m *primerv1alpha1.Export) *batchv1.Job {
-	mode := int32(0644)
+	credentials, credentialsMount := gitCredentialsVolume(m)
+	container := corev1.Container{
+		ImagePullPolicy: "IfNotPresent",
+		Image:           r.ExportImage,
+		Env: append([]corev1.EnvVar{
+			{Name: "REPO", Value: m.Spec.Repo},
+			{Name: "TARGET_PATH", Value: m.Spec.Path},
+			{Name: "MERGE_STRATEGY", Value: m.Spec.MergeStrategy},
+			{Name: "BRANCH", Value: m.Spec.Branch},
+			{Name: "EMAIL", Value: m.Spec.Email},
+			{Name: "AUTHOR_NAME", Value: m.Spec.AuthorName},
+			{Name: "COMMIT_MESSAGE", Value: m.Spec.CommitMessage},
+			{Name: "EXPORT_NAME", Value: m.Name},
+			{Name: "NAMESPACE", Value: m.Namespace},
+			{Name: "METHOD", Value: m.Spec.Method},
+			{Name: "USER", Value: m.Spec.User},
+			{Name: "SSH_KEY_NAME", Value: m.Spec.SSHKeyName},
+			{Name: "KNOWN_HOSTS", Value: m.Spec.KnownHosts},
+			{Name: "INSECURE_ACCEPT_HOST_KEYS", Value: strconv.FormatBool(m.Spec.InsecureAcceptHostKeys)},
+		}, append(append(append(selectionEnv(m), outputEnv(m)...), pullRequestEnv(m)...), bootstrapEnv(m)...)...),
+		VolumeMounts: []corev1.VolumeMount{
+			credentialsMount,
+			{Name: "output", MountPath: "/output"},
+			{Name: "home", MountPath: "/usr/local/app-root/src"},
+		},
+	}
+	volumes := []corev1.Volume{
+		{Name: "output", VolumeSource: outputVolumeSource(m)},
+		// The home directory holds the decrypted SSH key so keep it in memory
+		{Name: "home", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}}},
+		credentials,
+	}
+	if m.Spec.PullRequest != nil {
+		volumes = append(volumes, secretVolume("pull-request", m.Spec.PullRequest.TokenSecret))
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "pull-request", MountPath: "/pull-request"})
+	}
+	if m.Spec.Signing != nil {
+		volumes = append(volumes, secretVolume("signing", m.Spec.Signing.Secret))
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "signing", MountPath: "/signing"})
+		container.Env = append(container.Env, corev1.EnvVar{Name: "SIGNING_FORMAT", Value: m.Spec.Signing.Format})
+	}
+	if e := m.Spec.Encryption; e != nil {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "ENCRYPTION", Value: encryptionProvider(e)})
+		if len(e.AgeRecipients) != 0 {
+			container.Env = append(container.Env, corev1.EnvVar{Name: "SOPS_AGE_RECIPIENTS", Value: strings.Join(e.AgeRecipients, ",")})
+		}
+		if volume, ok := encryptionVolume(e); ok {
+			volumes = append(volumes, volume)
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "encryption", MountPath: "/encryption"})
+		}
+		if e.Certificate != nil {
+			key := e.Certificate.Key
+			if key == "" {
+				key = "cert.pem"
+			}
+			container.Env = append(container.Env, corev1.EnvVar{Name: "SEALED_SECRETS_CERT", Value: "/encryption/" + key})
+		}
+	}
+	if x := m.Spec.ExternalSecrets; x != nil {
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "EXTERNAL_SECRETS_STORE", Value: x.SecretStore},
+			corev1.EnvVar{Name: "EXTERNAL_SECRETS_STORE_KIND", Value: x.SecretStoreKind},
+			corev1.EnvVar{Name: "EXTERNAL_SECRETS_KEY_TEMPLATE", Value: x.KeyTemplate},
+			corev1.EnvVar{Name: "EXTERNAL_SECRETS_REFRESH_INTERVAL", Value: x.RefreshInterval},
+		)
+	}
+	if m.Spec.RedactSecrets {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "REDACT_SECRETS", Value: "true"})
+	}
+	if m.Spec.DryRun {
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "DRY_RUN", Value: "true"},
+			corev1.EnvVar{Name: "TIME", Value: m.ObjectMeta.CreationTimestamp.Rfc3339Copy().Format(time.RFC3339)},
+		)
+	}
+	if m.Spec.DriftCheck {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "DRIFT_CHECK", Value: "true"})
+	}
+	if sc := m.Spec.SecretScanning; sc != nil {
+		policy := sc.Policy
+		if policy == "" {
+			policy = "Fail"
+		}
+		patterns, _ := json.Marshal(sc.Patterns)
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "SECRET_SCANNING_POLICY", Value: policy},
+			corev1.EnvVar{Name: "SECRET_SCANNING_PATTERNS", Value: string(patterns)},
+		)
+	}
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "primer-export-" + m.Name,
 			Namespace: m.Namespace,
+			Labels:    map[string]string{exportLabel: m.Name},
 		},
 		Spec: batchv1.JobSpec{
+			BackoffLimit:          m.Spec.BackoffLimit,
+			ActiveDeadlineSeconds: m.Spec.ActiveDeadlineSeconds,
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{exportLabel: m.Name},
+				},
 				Spec: corev1.PodSpec{
 					RestartPolicy:      "Never",
 					ServiceAccountName: "primer-export-" + m.Name,
-					Containers: []corev1.Container{{
-						Name:            m.Name,
-						ImagePullPolicy: "IfNotPresent",
-						Image:           r.ExportImage,
-						Command:         []string{"/bin/sh", "-c", "/committer.sh"},
-						Env: []corev1.EnvVar{
-							{Name: "REPO", Value: m.Spec.Repo},
-							{Name: "BRANCH", Value: m.Spec.Branch},
-							{Name: "EMAIL", Value: m.Spec.Email},
-							{Name: "NAMESPACE", Value: m.Namespace},
-							{Name: "METHOD", Value: m.Spec.Method},
-							{Name: "USER", Value: m.Spec.User},
-						},
-						VolumeMounts: []corev1.VolumeMount{
-							{Name: "sshkeys", MountPath: "/keys"},
-							{Name: "output", MountPath: "/output"},
-						},
-					}},
-					Volumes: []corev1.Volume{
-						{Name: "output", VolumeSource: corev1.VolumeSource{
-							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-								ClaimName: "primer-export-" + m.Name,
-							},
-						},
-						},
-						{Name: "sshkeys", VolumeSource: corev1.VolumeSource{
-							Secret: &corev1.SecretVolumeSource{
-								SecretName:  m.Spec.Secret,
-								DefaultMode: &mode,
-							}},
-						},
-					},
+					InitContainers:     []corev1.Container{exportContainer(container, "export")},
+					Containers:         []corev1.Container{exportContainer(container, "push")},
+					Volumes:            volumes,
 				},
 			},
 		},
@@ -460,34 +1376,273 @@ func (r *ExportReconciler) jobGitForExport(m *primerv1alpha1.Export) *batchv1.Jo
 	return job
 }
 
+// pullRequestEnv returns the environment used to open a pull request for
+// the export, nothing when the export is pushed directly
+func pullRequestEnv(m *primerv1alpha1.Export) []corev1.EnvVar {
+	if m.Spec.PullRequest == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "PR_PROVIDER", Value: m.Spec.PullRequest.Provider},
+		{Name: "PR_API_URL", Value: m.Spec.PullRequest.APIURL},
+		{Name: "PR_REPOSITORY", Value: m.Spec.PullRequest.Repository},
+		{Name: "PR_HEAD", Value: m.Status.PullRequestHead},
+	}
+}
+
+// bootstrapEnv returns the environment used to generate the manifests
+// pointing a GitOps tool at the export
+func bootstrapEnv(m *primerv1alpha1.Export) []corev1.EnvVar {
+	b := m.Spec.Bootstrap
+	if b == nil {
+		return nil
+	}
+	mode := b.Mode
+	if mode == "" {
+		mode = "Commit"
+	}
+	return []corev1.EnvVar{
+		{Name: "BOOTSTRAP_TOOL", Value: b.Tool},
+		{Name: "BOOTSTRAP_MODE", Value: mode},
+		{Name: "BOOTSTRAP_PATH", Value: b.Path},
+		{Name: "BOOTSTRAP_NAMESPACE", Value: b.Namespace},
+		{Name: "BOOTSTRAP_PROJECT", Value: b.Project},
+		{Name: "BOOTSTRAP_SECRET_REF", Value: b.SecretRef},
+	}
+}
+
+// gitCredentialsVolume returns the volume holding the credentials used to
+// clone and push. HTTPS credentials are mounted at /credentials, otherwise
+// the SSH key is mounted at /keys
+func gitCredentialsVolume(m *primerv1alpha1.Export) (corev1.Volume, corev1.VolumeMount) {
+	name, secretName, mountPath := "sshkeys", m.Spec.Secret, "/keys"
+	if m.Spec.HTTPSSecret != "" {
+		name, secretName, mountPath = "credentials", m.Spec.HTTPSSecret, "/credentials"
+	}
+	return secretVolume(name, secretName), corev1.VolumeMount{Name: name, MountPath: mountPath}
+}
+
+// encryptionVolume returns the volume mounted at /encryption holding either
+// the age recipients or the certificate Secrets are sealed with
+func encryptionVolume(e *primerv1alpha1.EncryptionSpec) (corev1.Volume, bool) {
+	switch {
+	case e.Secret != "":
+		return secretVolume("encryption", e.Secret), true
+	case e.Certificate != nil && e.Certificate.Secret != "":
+		return secretVolume("encryption", e.Certificate.Secret), true
+	case e.Certificate != nil && e.Certificate.ConfigMap != "":
+		return corev1.Volume{Name: "encryption", VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: e.Certificate.ConfigMap},
+			}},
+		}, true
+	}
+	return corev1.Volume{}, false
+}
+
+// secretVolume returns a volume named name holding the keys of secretName
+func secretVolume(name, secretName string) corev1.Volume {
+	mode := int32(0644)
+	return corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{
+		Secret: &corev1.SecretVolumeSource{
+			SecretName:  secretName,
+			DefaultMode: &mode,
+		}},
+	}
+}
+
+// cronJobGitForExport returns a CronJob that runs the git export on a schedule
+func (r *ExportReconciler) cronJobGitForExport(m *primerv1alpha1.Export) *batchv1.CronJob {
+	concurrencyPolicy := batchv1.ForbidConcurrent
+	if m.Spec.ConcurrencyPolicy != "" {
+		concurrencyPolicy = batchv1.ConcurrencyPolicy(m.Spec.ConcurrencyPolicy)
+	}
+	jobTemplate := batchv1.JobTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{exportLabel: m.Name},
+		},
+		Spec: r.jobGitForExport(m).Spec,
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "primer-export-" + m.Name,
+			Namespace:   m.Namespace,
+			Annotations: map[string]string{templateHashAnnotation: templateHash(jobTemplate)},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   m.Spec.Schedule,
+			ConcurrencyPolicy:          concurrencyPolicy,
+			SuccessfulJobsHistoryLimit: m.Spec.SuccessfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     m.Spec.FailedJobsHistoryLimit,
+			JobTemplate:                jobTemplate,
+		},
+	}
+	ctrl.SetControllerReference(m, cronJob, r.Scheme)
+	return cronJob
+}
+
+// updateCronJob copies the scheduling fields and the Job template of
+// desired onto found and reports whether anything changed. The template is
+// compared by its hash as the API server fills in defaults
+func updateCronJob(found, desired *batchv1.CronJob) bool {
+	if found.Spec.Schedule == desired.Spec.Schedule &&
+		found.Spec.ConcurrencyPolicy == desired.Spec.ConcurrencyPolicy &&
+		reflect.DeepEqual(found.Spec.SuccessfulJobsHistoryLimit, desired.Spec.SuccessfulJobsHistoryLimit) &&
+		reflect.DeepEqual(found.Spec.FailedJobsHistoryLimit, desired.Spec.FailedJobsHistoryLimit) &&
+		found.Annotations[templateHashAnnotation] == desired.Annotations[templateHashAnnotation] {
+		return false
+	}
+	found.Spec.Schedule = desired.Spec.Schedule
+	found.Spec.ConcurrencyPolicy = desired.Spec.ConcurrencyPolicy
+	found.Spec.SuccessfulJobsHistoryLimit = desired.Spec.SuccessfulJobsHistoryLimit
+	found.Spec.FailedJobsHistoryLimit = desired.Spec.FailedJobsHistoryLimit
+	found.Spec.JobTemplate = desired.Spec.JobTemplate
+	setTemplateHash(found, desired.Annotations[templateHashAnnotation])
+	return true
+}
+
+// templateHash returns the hash recorded in templateHashAnnotation for a
+// template
+func templateHash(template interface{}) string {
+	data, _ := json.Marshal(template)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// setTemplateHash records hash in the annotations of obj
+func setTemplateHash(obj metav1.Object, hash string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[templateHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+}
+
+// exportContainer returns a copy of c that runs the given stage of the
+// export script. The end of the log is kept as the termination message
+// when the stage fails
+func exportContainer(c corev1.Container, stage string) corev1.Container {
+	c.Name = stage
+	c.Command = []string{"/bin/sh", "-c", "/committer.sh " + stage}
+	c.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	return c
+}
+
+// syncDeploymentForExport returns a Deployment that runs the git export
+// each time the namespace changes
+func (r *ExportReconciler) syncDeploymentForExport(m *primerv1alpha1.Export) *appsv1.Deployment {
+	// The delays have already been checked by validateExport
+	debounce, maxDelay, _ := continuousDelays(m.Spec.Continuous)
+	template := r.jobGitForExport(m).Spec.Template
+	container := template.Spec.Containers[0]
+	container.Name = "sync"
+	container.Command = []string{"primer-export", "watch", "-debounce", debounce.String(), "-max-delay", maxDelay.String(), "/committer.sh"}
+	template.Spec.InitContainers = nil
+	template.Spec.Containers = []corev1.Container{container}
+	template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "primer-sync-" + m.Name,
+			Namespace:   m.Namespace,
+			Labels:      map[string]string{exportLabel: m.Name},
+			Annotations: map[string]string{templateHashAnnotation: templateHash(template)},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{exportLabel: m.Name},
+			},
+			// Only one export may push to the branch at a time
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: template,
+		},
+	}
+	ctrl.SetControllerReference(m, deployment, r.Scheme)
+	return deployment
+}
+
+// updateSyncDeployment copies the pod template of desired onto found and
+// reports whether anything changed. The template is compared by its hash
+// as the API server fills in defaults
+func updateSyncDeployment(found, desired *appsv1.Deployment) bool {
+	if found.Annotations[templateHashAnnotation] == desired.Annotations[templateHashAnnotation] {
+		return false
+	}
+	found.Spec.Template = desired.Spec.Template
+	setTemplateHash(found, desired.Annotations[templateHashAnnotation])
+	return true
+}
+
+// continuousDelays returns the debounce and maximum delay of a continuous
+// export
+func continuousDelays(c *primerv1alpha1.ContinuousSpec) (time.Duration, time.Duration, error) {
+	debounce, maxDelay := 10*time.Second, time.Minute
+	var err error
+	if c.Debounce != "" {
+		if debounce, err = time.ParseDuration(c.Debounce); err != nil {
+			return 0, 0, fmt.Errorf("invalid continuous debounce: %w", err)
+		}
+	}
+	if c.MaxDelay != "" {
+		if maxDelay, err = time.ParseDuration(c.MaxDelay); err != nil {
+			return 0, 0, fmt.Errorf("invalid continuous maxDelay: %w", err)
+		}
+	}
+	if debounce <= 0 || maxDelay < debounce {
+		return 0, 0, fmt.Errorf("continuous debounce must be positive and no longer than maxDelay")
+	}
+	return debounce, maxDelay, nil
+}
+
+// outputVolumeSource returns the volume the export is written to. Scheduled
+// and continuous exports get an emptyDir as every run starts afresh
+func outputVolumeSource(m *primerv1alpha1.Export) corev1.VolumeSource {
+	if m.Spec.Schedule != "" || m.Spec.Continuous != nil {
+		return corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}
+	}
+	return corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: "primer-export-" + m.Name,
+		},
+	}
+}
+
 // jobGitForExport returns a instance Job object
 func (r *ExportReconciler) jobDownloadForExport(m *primerv1alpha1.Export) *batchv1.Job {
+	container := corev1.Container{
+		ImagePullPolicy: "IfNotPresent",
+		Image:           r.ExportImage,
+		Env: append([]corev1.EnvVar{
+			{Name: "METHOD", Value: m.Spec.Method},
+			{Name: "NAMESPACE", Value: m.Namespace},
+			{Name: "EXPORT_NAME", Value: m.Name},
+			{Name: "USER", Value: m.Spec.User},
+			{Name: "TIME", Value: m.ObjectMeta.CreationTimestamp.Rfc3339Copy().Format(time.RFC3339)},
+		}, append(selectionEnv(m), outputEnv(m)...)...),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "output", MountPath: "/output"},
+		},
+	}
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "primer-export-" + m.Name,
 			Namespace: m.Namespace,
+			Labels:    map[string]string{exportLabel: m.Name},
 		},
 		Spec: batchv1.JobSpec{
+			BackoffLimit:          m.Spec.BackoffLimit,
+			ActiveDeadlineSeconds: m.Spec.ActiveDeadlineSeconds,
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{exportLabel: m.Name},
+				},
 				Spec: corev1.PodSpec{
 					RestartPolicy:      "Never",
 					ServiceAccountName: "primer-export-" + m.Name,
-					Containers: []corev1.Container{{
-						Name:            m.Name,
-						ImagePullPolicy: "IfNotPresent",
-						Image:           r.ExportImage,
-						Command:         []string{"/bin/sh", "-c", "/committer.sh"},
-						Env: []corev1.EnvVar{
-							{Name: "METHOD", Value: m.Spec.Method},
-							{Name: "NAMESPACE", Value: m.Namespace},
-							{Name: "EXPORT_NAME", Value: m.Name},
-							{Name: "USER", Value: m.Spec.User},
-							{Name: "TIME", Value: m.ObjectMeta.CreationTimestamp.Rfc3339Copy().Format(time.RFC3339)},
-						},
-						VolumeMounts: []corev1.VolumeMount{
-							{Name: "output", MountPath: "/output"},
-						},
-					}},
+					InitContainers:     []corev1.Container{exportContainer(container, "export")},
+					Containers:         []corev1.Container{exportContainer(container, "push")},
 					Volumes: []corev1.Volume{
 						{Name: "output", VolumeSource: corev1.VolumeSource{
 							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
@@ -605,8 +1760,8 @@ func (r *ExportReconciler) clusterRoleGenerate(m *primerv1alpha1.Export) *rbacv1
 	// Define a new clusterRole object
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "primer-export-" + m.Namespace + "-" + m.Name,
-			Namespace: m.Namespace,
+			Name:   "primer-export-" + m.Namespace + "-" + m.Name,
+			Labels: clusterObjectLabels(m),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -618,7 +1773,6 @@ func (r *ExportReconciler) clusterRoleGenerate(m *primerv1alpha1.Export) *rbacv1
 		},
 	}
 	// ClusterRole reconcile finished
-	ctrl.SetControllerReference(m, clusterRole, r.Scheme)
 	return clusterRole
 }
 
@@ -626,8 +1780,8 @@ func (r *ExportReconciler) clusterRoleBindingGenerate(m *primerv1alpha1.Export)
 	// Define a new ClusterRole binding object
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "primer-export-" + m.Namespace + "-" + m.Name,
-			Namespace: m.Namespace,
+			Name:   "primer-export-" + m.Namespace + "-" + m.Name,
+			Labels: clusterObjectLabels(m),
 		},
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: "rbac.authorization.k8s.io",
@@ -639,7 +1793,6 @@ func (r *ExportReconciler) clusterRoleBindingGenerate(m *primerv1alpha1.Export)
 		},
 	}
 	// ClusterRole Binding reconcile finished
-	ctrl.SetControllerReference(m, clusterRoleBinding, r.Scheme)
 	return clusterRoleBinding
 }
 
@@ -803,11 +1956,27 @@ func (r *ExportReconciler) netPolGenerate(m *primerv1alpha1.Export) *networkingv
 	return networkPolicy
 }
 
+// servesDownload reports whether the output of the export is served for
+// download once the export job completes, the zip of the download method
+// or the diff of a dry run
+func servesDownload(m *primerv1alpha1.Export) bool {
+	return m.Spec.Method == "download" || m.Spec.DryRun
+}
+
 // Check to see if job is completed
 func isJobComplete(job *batchv1.Job) bool {
 	return job.Status.Succeeded == 1
 }
 
+func isJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
 // Identify route to be used for status
 func defineRoute(route *routev1.Route) string {
 	return route.Spec.Host
@@ -818,6 +1987,19 @@ func isDeploymentReady(deployment *appsv1.Deployment) bool {
 	return deployment.Status.ReadyReplicas == 1
 }
 
+// CacheSelectors restricts the objects cached by the manager to the ones
+// the controller needs. Only the Pods of exports are watched rather than
+// every Pod in the cluster
+func CacheSelectors() cache.SelectorsByObject {
+	exported, err := labels.NewRequirement(exportLabel, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	return cache.SelectorsByObject{
+		&corev1.Pod{}: {Label: labels.NewSelector().Add(*exported)},
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	DownloaderImage := os.Getenv("DownloaderImageName")
@@ -840,8 +2022,9 @@ func (r *ExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&primerv1alpha1.Export{}).
 		Owns(&batchv1.Job{}).
-		Owns(&rbacv1.ClusterRole{}).
-		Owns(&rbacv1.ClusterRoleBinding{}).
+		Owns(&batchv1.CronJob{}).
+		Watches(&source.Kind{Type: &rbacv1.ClusterRole{}}, handler.EnqueueRequestsFromMapFunc(clusterObjectToExport)).
+		Watches(&source.Kind{Type: &rbacv1.ClusterRoleBinding{}}, handler.EnqueueRequestsFromMapFunc(clusterObjectToExport)).
 		Owns(&corev1.ServiceAccount{}).
 		Owns(&corev1.PersistentVolumeClaim{}).
 		Owns(&corev1.Service{}).
@@ -849,5 +2032,6 @@ func (r *ExportReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.Secret{}).
 		Owns(&routev1.Route{}).
 		Owns(&networkingv1.NetworkPolicy{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(podToExport)).
 		Complete(r)
 }
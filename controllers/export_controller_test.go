@@ -0,0 +1,565 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/operator-framework/operator-lib/status"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
+)
+
+// newTestReconciler returns a reconciler backed by a fake client holding
+// objs, for tests that do not need a running API server
+func newTestReconciler(t *testing.T, objs ...client.Object) *ExportReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, routev1.AddToScheme, primerv1alpha1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &ExportReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme: scheme,
+	}
+}
+
+// gitExport returns an Export of namespace/name pushing to the main branch
+// of repo, created age ago
+func gitExport(namespace, name, repo string, age time.Duration) *primerv1alpha1.Export {
+	return &primerv1alpha1.Export{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Add(-age)),
+		},
+		Spec: primerv1alpha1.ExportSpec{
+			Method: "git",
+			Repo:   repo,
+			Branch: "main",
+		},
+	}
+}
+
+func TestSharedTarget(t *testing.T) {
+	const repo = "https://example.com/org/gitops.git"
+	tests := map[string]struct {
+		others []*primerv1alpha1.Export
+		export *primerv1alpha1.Export
+		owner  string
+	}{
+		"only export": {
+			export: gitExport("demo", "export", repo, 0),
+		},
+		"older export of the namespace": {
+			others: []*primerv1alpha1.Export{gitExport("demo", "first", repo, time.Hour)},
+			export: gitExport("demo", "second", repo, 0),
+			owner:  "demo/first",
+		},
+		"newer export of the namespace": {
+			others: []*primerv1alpha1.Export{gitExport("demo", "second", repo, 0)},
+			export: gitExport("demo", "first", repo, time.Hour),
+		},
+		"oldest of several": {
+			others: []*primerv1alpha1.Export{
+				gitExport("demo", "b", repo, time.Hour),
+				gitExport("demo", "a", repo, 2*time.Hour),
+			},
+			export: gitExport("demo", "c", repo, 0),
+			owner:  "demo/a",
+		},
+		"created in the same second": {
+			others: []*primerv1alpha1.Export{gitExport("demo", "a", repo, 0)},
+			export: gitExport("demo", "b", repo, 0),
+			owner:  "demo/a",
+		},
+		"same path from another namespace": {
+			others: []*primerv1alpha1.Export{func() *primerv1alpha1.Export {
+				m := gitExport("other", "export", repo, time.Hour)
+				m.Spec.Path = "demo/"
+				return m
+			}()},
+			export: gitExport("demo", "export", repo, 0),
+			owner:  "other/export",
+		},
+		"repository without .git": {
+			others: []*primerv1alpha1.Export{gitExport("demo", "first", "https://example.com/org/gitops", time.Hour)},
+			export: gitExport("demo", "second", repo, 0),
+			owner:  "demo/first",
+		},
+		"path of its own": {
+			others: []*primerv1alpha1.Export{gitExport("demo", "first", repo, time.Hour)},
+			export: func() *primerv1alpha1.Export {
+				m := gitExport("demo", "second", repo, 0)
+				m.Spec.Path = "demo/second"
+				return m
+			}(),
+		},
+		"another branch": {
+			others: []*primerv1alpha1.Export{gitExport("demo", "first", repo, time.Hour)},
+			export: func() *primerv1alpha1.Export {
+				m := gitExport("demo", "second", repo, 0)
+				m.Spec.Branch = "staging"
+				return m
+			}(),
+		},
+		"completed one off export": {
+			others: []*primerv1alpha1.Export{func() *primerv1alpha1.Export {
+				m := gitExport("demo", "first", repo, time.Hour)
+				m.Status.Completed = true
+				return m
+			}()},
+			export: gitExport("demo", "second", repo, 0),
+		},
+		"completed scheduled export": {
+			others: []*primerv1alpha1.Export{func() *primerv1alpha1.Export {
+				m := gitExport("demo", "first", repo, time.Hour)
+				m.Spec.Schedule = "@daily"
+				m.Status.Completed = true
+				return m
+			}()},
+			export: gitExport("demo", "second", repo, 0),
+			owner:  "demo/first",
+		},
+		"dry run": {
+			others: []*primerv1alpha1.Export{func() *primerv1alpha1.Export {
+				m := gitExport("demo", "first", repo, time.Hour)
+				m.Spec.DryRun = true
+				return m
+			}()},
+			export: gitExport("demo", "second", repo, 0),
+		},
+		"download": {
+			others: []*primerv1alpha1.Export{func() *primerv1alpha1.Export {
+				m := gitExport("demo", "first", "", time.Hour)
+				m.Spec.Method = "download"
+				return m
+			}()},
+			export: func() *primerv1alpha1.Export {
+				m := gitExport("demo", "second", "", 0)
+				m.Spec.Method = "download"
+				return m
+			}(),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			objs := []client.Object{test.export}
+			for _, other := range test.others {
+				objs = append(objs, other)
+			}
+			r := newTestReconciler(t, objs...)
+			owner, err := r.sharedTarget(context.TODO(), test.export)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if owner != nil {
+				got = owner.Namespace + "/" + owner.Name
+			}
+			if got != test.owner {
+				t.Errorf("owner = %q, want %q", got, test.owner)
+			}
+		})
+	}
+}
+
+func TestValidateExportSchedule(t *testing.T) {
+	tests := map[string]struct {
+		spec primerv1alpha1.ExportSpec
+		err  string
+	}{
+		"scheduled": {
+			spec: primerv1alpha1.ExportSpec{Method: "git", Schedule: "0 2 * * *"},
+		},
+		"scheduled download": {
+			spec: primerv1alpha1.ExportSpec{Method: "download", Schedule: "0 2 * * *"},
+			err:  `schedule is not supported by the "download" method`,
+		},
+		"download": {
+			spec: primerv1alpha1.ExportSpec{Method: "download"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateExport(&primerv1alpha1.Export{Spec: test.spec})
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Errorf("error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+// scheduledExport returns an Export of the demo namespace run every night
+func scheduledExport() *primerv1alpha1.Export {
+	m := gitExport("demo", "nightly", "git@example.com:org/gitops.git", 0)
+	m.Spec.Schedule = "0 2 * * *"
+	return m
+}
+
+// applyDefaults changes a CronJob the way the API server defaults it when
+// it is stored, so it no longer equals the CronJob it was created from
+func applyDefaults(cronJob *batchv1.CronJob) {
+	spec := &cronJob.Spec.JobTemplate.Spec.Template.Spec
+	spec.DNSPolicy = corev1.DNSClusterFirst
+	spec.SchedulerName = corev1.DefaultSchedulerName
+	for i := range spec.Containers {
+		spec.Containers[i].TerminationMessagePath = corev1.TerminationMessagePathDefault
+		spec.Containers[i].ImagePullPolicy = corev1.PullIfNotPresent
+	}
+}
+
+func TestUpdateCronJob(t *testing.T) {
+	r := newTestReconciler(t)
+	m := scheduledExport()
+	found := r.cronJobGitForExport(m)
+	applyDefaults(found)
+	defaulted := found.DeepCopy()
+
+	if updateCronJob(found, r.cronJobGitForExport(m)) {
+		t.Error("updated a CronJob that only differs by the defaults of the API server")
+	}
+	if !reflect.DeepEqual(found, defaulted) {
+		t.Error("CronJob changed without an update being reported")
+	}
+
+	tests := map[string]func(m *primerv1alpha1.Export){
+		"schedule": func(m *primerv1alpha1.Export) { m.Spec.Schedule = "@hourly" },
+		"history limit": func(m *primerv1alpha1.Export) {
+			limit := int32(5)
+			m.Spec.FailedJobsHistoryLimit = &limit
+		},
+		"job template": func(m *primerv1alpha1.Export) { m.Spec.Branch = "staging" },
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			changed := scheduledExport()
+			change(changed)
+			desired := r.cronJobGitForExport(changed)
+			found := defaulted.DeepCopy()
+			if !updateCronJob(found, desired) {
+				t.Fatal("change was not picked up")
+			}
+			if !reflect.DeepEqual(found.Spec, desired.Spec) {
+				t.Errorf("spec = %+v, want %+v", found.Spec, desired.Spec)
+			}
+			if found.Annotations[templateHashAnnotation] != desired.Annotations[templateHashAnnotation] {
+				t.Error("template hash was not updated")
+			}
+			// The next reconcile leaves the updated CronJob alone
+			applyDefaults(found)
+			if updateCronJob(found, r.cronJobGitForExport(changed)) {
+				t.Error("updated again")
+			}
+		})
+	}
+
+	// CronJobs created before the hash was recorded are updated once
+	found = defaulted.DeepCopy()
+	found.Annotations = nil
+	if !updateCronJob(found, r.cronJobGitForExport(m)) || found.Annotations[templateHashAnnotation] == "" {
+		t.Error("CronJob without a template hash was not updated")
+	}
+}
+
+func TestPullRequestEnv(t *testing.T) {
+	r := newTestReconciler(t)
+	m := scheduledExport()
+	if env := pullRequestEnv(m); env != nil {
+		t.Errorf("env of an export pushed directly = %v", env)
+	}
+
+	m.Spec.PullRequest = &primerv1alpha1.PullRequestSpec{Provider: "github", Repository: "org/gitops"}
+	found := r.cronJobGitForExport(m)
+	applyDefaults(found)
+	m.Status.PullRequestHead = "0123abcd"
+	env := map[string]string{}
+	for _, e := range pullRequestEnv(m) {
+		env[e.Name] = e.Value
+	}
+	if env["PR_PROVIDER"] != "github" || env["PR_REPOSITORY"] != "org/gitops" || env["PR_HEAD"] != "0123abcd" {
+		t.Errorf("env = %v", env)
+	}
+	// The next run leases the branch against the commit last pushed
+	if !updateCronJob(found, r.cronJobGitForExport(m)) {
+		t.Error("CronJob was not updated with the commit last pushed")
+	}
+}
+
+// exportJob returns the Job of an export run along with its Pods, which
+// have the given container statuses
+func exportJob(jobStatus batchv1.JobStatus, pods ...[]corev1.ContainerStatus) (*batchv1.Job, []client.Object) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "primer-export-demo"},
+		Status:     jobStatus,
+	}
+	objs := []client.Object{job}
+	for i, statuses := range pods {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "demo",
+			Name:      fmt.Sprintf("primer-export-demo-%d", i),
+			Labels:    map[string]string{"job-name": job.Name},
+		}}
+		for _, s := range statuses {
+			// The export stage runs as an init container
+			if s.Name == "export" {
+				pod.Status.InitContainerStatuses = append(pod.Status.InitContainerStatuses, s)
+			} else {
+				pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, s)
+			}
+		}
+		objs = append(objs, pod)
+	}
+	return job, objs
+}
+
+func running(name string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+}
+
+func waiting(name string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}}}
+}
+
+func TestJobPhase(t *testing.T) {
+	tests := map[string]struct {
+		status batchv1.JobStatus
+		pods   [][]corev1.ContainerStatus
+		phase  primerv1alpha1.ExportPhase
+	}{
+		"no pod yet": {
+			phase: primerv1alpha1.ExportPhaseRunning,
+		},
+		"exporting": {
+			pods:  [][]corev1.ContainerStatus{{running("export"), waiting("push")}},
+			phase: primerv1alpha1.ExportPhaseRunning,
+		},
+		"pushing": {
+			pods:  [][]corev1.ContainerStatus{{running("push")}},
+			phase: primerv1alpha1.ExportPhasePushing,
+		},
+		"pushing after a failed pod": {
+			pods:  [][]corev1.ContainerStatus{{waiting("push")}, {running("push")}},
+			phase: primerv1alpha1.ExportPhasePushing,
+		},
+		"complete": {
+			status: batchv1.JobStatus{Succeeded: 1},
+			pods:   [][]corev1.ContainerStatus{{running("push")}},
+			phase:  primerv1alpha1.ExportPhaseSucceeded,
+		},
+		"failed": {
+			status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}},
+			phase:  primerv1alpha1.ExportPhaseFailed,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			job, objs := exportJob(test.status, test.pods...)
+			phase, err := newTestReconciler(t, objs...).jobPhase(context.TODO(), job)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if phase != test.phase {
+				t.Errorf("phase = %s, want %s", phase, test.phase)
+			}
+		})
+	}
+}
+
+func TestSetPhase(t *testing.T) {
+	m := &primerv1alpha1.Export{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+	for _, phase := range []primerv1alpha1.ExportPhase{
+		primerv1alpha1.ExportPhasePending,
+		primerv1alpha1.ExportPhaseRunning,
+		primerv1alpha1.ExportPhaseSucceeded,
+		primerv1alpha1.ExportPhaseFailed,
+	} {
+		setPhase(m, phase)
+		completed := m.Status.Conditions.GetCondition(primerv1alpha1.ConditionCompleted)
+		if m.Status.Phase != phase || completed == nil || string(completed.Reason) != string(phase) {
+			t.Fatalf("%s: phase = %s, condition = %+v", phase, m.Status.Phase, completed)
+		}
+		if want := phase == primerv1alpha1.ExportPhaseSucceeded; completed.IsTrue() != want {
+			t.Errorf("%s: Completed = %s", phase, completed.Status)
+		}
+		if m.Status.ObservedGeneration != 3 {
+			t.Errorf("%s: observedGeneration = %d", phase, m.Status.ObservedGeneration)
+		}
+	}
+}
+
+// terminated returns the status of a container that exited with code at
+// minute of the run, leaving message
+func terminated(name string, code int32, minute int, message string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+		ExitCode:   code,
+		FinishedAt: metav1.NewTime(time.Date(2021, 6, 1, 0, minute, 0, 0, time.UTC)),
+		Message:    message,
+	}}}
+}
+
+func TestJobFailedCondition(t *testing.T) {
+	backoffLimitExceeded := batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+		Type:    batchv1.JobFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  "BackoffLimitExceeded",
+		Message: "Job has reached the specified backoff limit",
+	}}}
+	log := ""
+	for i := 1; i <= 30; i++ {
+		log += fmt.Sprintf("line %d\n", i)
+	}
+
+	job, objs := exportJob(backoffLimitExceeded,
+		[]corev1.ContainerStatus{terminated("export", 1, 1, "crane: forbidden")},
+		// The latest failure is reported, successful containers are not
+		[]corev1.ContainerStatus{terminated("export", 0, 2, ""), terminated("push", 128, 3, log)},
+		[]corev1.ContainerStatus{terminated("export", 0, 4, "")},
+	)
+	failed, err := newTestReconciler(t, objs...).jobFailedCondition(context.TODO(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Type != primerv1alpha1.ConditionFailed || failed.Status != corev1.ConditionTrue || failed.Reason != "BackoffLimitExceeded" {
+		t.Errorf("condition = %+v", failed)
+	}
+	want := "Job has reached the specified backoff limit: push container exited with code 128: line 11\n"
+	if !strings.HasPrefix(failed.Message, want) || !strings.HasSuffix(failed.Message, "\nline 30") {
+		t.Errorf("message = %q, want the last 20 lines of the push log", failed.Message)
+	}
+
+	// The Pods may already be gone
+	job, objs = exportJob(batchv1.JobStatus{})
+	failed, err = newTestReconciler(t, objs...).jobFailedCondition(context.TODO(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Reason != status.ConditionReason(batchv1.JobFailed) || failed.Message != "Export job failed" {
+		t.Errorf("condition without Pods = %+v", failed)
+	}
+}
+
+// reconcileExport runs Reconcile for the Export demo/export until it stops
+// asking to be requeued
+func reconcileExport(t *testing.T, r *ExportReconciler) {
+	t.Helper()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "demo", Name: "export"}}
+	for i := 0; i < 20; i++ {
+		res, err := r.Reconcile(context.TODO(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Requeue {
+			return
+		}
+	}
+	t.Fatal("still requeued after 20 reconciles")
+}
+
+func TestReconcileSecretsDetected(t *testing.T) {
+	// The fake client looks up the cluster scoped objects of the export in
+	// the namespace they are asked for in
+	meta := metav1.ObjectMeta{Namespace: "demo", Name: "primer-export-demo-export"}
+	r := newTestReconciler(t, gitExport("demo", "export", "git@example.com:org/gitops.git", 0),
+		&rbacv1.ClusterRole{ObjectMeta: meta}, &rbacv1.ClusterRoleBinding{ObjectMeta: meta})
+	reconcileExport(t, r)
+
+	// The push stage hands the findings of the scan back without failing
+	job := &batchv1.Job{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: "demo", Name: "primer-export-export"}, job); err != nil {
+		t.Fatal(err)
+	}
+	job.Status = batchv1.JobStatus{Succeeded: 1}
+	if err := r.Status().Update(context.TODO(), job); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "primer-export-export-1", Labels: map[string]string{"job-name": job.Name}},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{terminated("export", 0, 1, "")},
+			ContainerStatuses:     []corev1.ContainerStatus{terminated("push", 0, 2, `{"secretsDetected":["ConfigMap/app data.DB_PASSWORD (credential-configmap-key)"]}`)},
+		},
+	}
+	if err := r.Create(context.TODO(), pod); err != nil {
+		t.Fatal(err)
+	}
+	reconcileExport(t, r)
+
+	m := &primerv1alpha1.Export{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: "demo", Name: "export"}, m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Status.Phase != primerv1alpha1.ExportPhaseFailed || !m.Status.Completed {
+		t.Errorf("phase = %s, completed = %v, want a finished failed export", m.Status.Phase, m.Status.Completed)
+	}
+	if failed := m.Status.Conditions.GetCondition(primerv1alpha1.ConditionFailed); failed == nil || failed.Reason != status.ConditionReason(primerv1alpha1.ConditionSecretsDetected) {
+		t.Errorf("Failed condition = %+v", failed)
+	}
+	detected := m.Status.Conditions.GetCondition(primerv1alpha1.ConditionSecretsDetected)
+	if detected == nil || detected.Reason != primerv1alpha1.SecretsDetectedReasonFailed || !strings.Contains(detected.Message, "ConfigMap/app data.DB_PASSWORD") {
+		t.Errorf("SecretsDetected condition = %+v", detected)
+	}
+	// The Job is cleaned up rather than run again
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: "demo", Name: job.Name}, &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("Job was left in place: %v", err)
+	}
+}
+
+func TestLogExcerpt(t *testing.T) {
+	if got := logExcerpt("\nfatal: repository not found\n\n"); got != "fatal: repository not found" {
+		t.Errorf("excerpt = %q", got)
+	}
+}
+
+func TestUpdateSyncDeployment(t *testing.T) {
+	r := newTestReconciler(t)
+	continuous := func(debounce string) *primerv1alpha1.Export {
+		m := gitExport("demo", "sync", "git@example.com:org/gitops.git", 0)
+		m.Spec.Continuous = &primerv1alpha1.ContinuousSpec{Debounce: debounce}
+		return m
+	}
+	found := r.syncDeploymentForExport(continuous(""))
+	// Defaulted by the API server
+	found.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
+	found.Spec.Template.Spec.Containers[0].TerminationMessagePath = corev1.TerminationMessagePathDefault
+	defaulted := found.DeepCopy()
+
+	if updateSyncDeployment(found, r.syncDeploymentForExport(continuous(""))) || !reflect.DeepEqual(found, defaulted) {
+		t.Error("updated a Deployment that only differs by the defaults of the API server")
+	}
+
+	desired := r.syncDeploymentForExport(continuous("30s"))
+	if !updateSyncDeployment(found, desired) {
+		t.Fatal("new debounce was not picked up")
+	}
+	// The whole template is replaced, so nothing of the old one lingers
+	if !reflect.DeepEqual(found.Spec.Template, desired.Spec.Template) {
+		t.Errorf("template = %+v, want %+v", found.Spec.Template, desired.Spec.Template)
+	}
+	if command := strings.Join(found.Spec.Template.Spec.Containers[0].Command, " "); !strings.Contains(command, "-debounce 30s") {
+		t.Errorf("command = %s", command)
+	}
+	if updateSyncDeployment(found, r.syncDeploymentForExport(continuous("30s"))) {
+		t.Error("updated again")
+	}
+}
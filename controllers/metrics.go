@@ -0,0 +1,79 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
+)
+
+// Drift checks are reported on the metrics endpoint of the manager so that
+// they can be alerted on. Each series is labelled with the namespace and
+// name of its Export
+var (
+	driftedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "primer_export_drifted",
+		Help: "Whether the cluster differs from the exported repository, 1 when it does",
+	}, []string{"namespace", "export"})
+	driftedObjectsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "primer_export_drifted_objects",
+		Help: "Number of objects that differ between the cluster and the exported repository",
+	}, []string{"namespace", "export", "state"})
+	driftLastCheckGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "primer_export_drift_last_check_timestamp_seconds",
+		Help: "Time the last drift check of the export completed",
+	}, []string{"namespace", "export"})
+)
+
+// driftStates are the states objects are counted by
+var driftStates = []primerv1alpha1.DriftState{
+	primerv1alpha1.DriftStateModified,
+	primerv1alpha1.DriftStateNotInRepository,
+	primerv1alpha1.DriftStateNotInCluster,
+}
+
+func init() {
+	metrics.Registry.MustRegister(driftedGauge, driftedObjectsGauge, driftLastCheckGauge)
+}
+
+// setDriftMetrics records the outcome of a drift check, counts holds the
+// number of drifted objects in each state
+func setDriftMetrics(instance *primerv1alpha1.Export, counts map[primerv1alpha1.DriftState]int) {
+	drifted := 0.0
+	for _, state := range driftStates {
+		driftedObjectsGauge.WithLabelValues(instance.Namespace, instance.Name, string(state)).Set(float64(counts[state]))
+		if counts[state] != 0 {
+			drifted = 1
+		}
+	}
+	driftedGauge.WithLabelValues(instance.Namespace, instance.Name).Set(drifted)
+	if t := instance.Status.Drift.LastCheckTime; t != nil {
+		driftLastCheckGauge.WithLabelValues(instance.Namespace, instance.Name).Set(float64(t.Unix()))
+	}
+}
+
+// deleteDriftMetrics removes the series of an Export that is deleted or no
+// longer checked for drift
+func deleteDriftMetrics(namespace, name string) {
+	driftedGauge.DeleteLabelValues(namespace, name)
+	driftLastCheckGauge.DeleteLabelValues(namespace, name)
+	for _, state := range driftStates {
+		driftedObjectsGauge.DeleteLabelValues(namespace, name, string(state))
+	}
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+)
+
+func TestSetDrift(t *testing.T) {
+	m := gitExport("drift", "nightly", "git@example.com:org/gitops.git", 0)
+	checked := metav1.NewTime(time.Date(2021, 6, 1, 2, 0, 0, 0, time.UTC))
+	defer deleteDriftMetrics(m.Namespace, m.Name)
+
+	// The lists were cut down to fit in the termination message
+	setDrift(m, &result.Diff{
+		Added:        []string{"Service/web"},
+		Changed:      []string{"Deployment/web", "ConfigMap/settings"},
+		ChangedCount: 5,
+		Removed:      []string{"Secret/creds"},
+	}, &checked)
+
+	drift := m.Status.Drift
+	if drift.Count != 7 || !drift.LastCheckTime.Equal(&checked) {
+		t.Errorf("count = %d, last check = %v", drift.Count, drift.LastCheckTime)
+	}
+	want := []primerv1alpha1.DriftedObject{
+		{Object: "ConfigMap/settings", State: primerv1alpha1.DriftStateModified},
+		{Object: "Deployment/web", State: primerv1alpha1.DriftStateModified},
+		{Object: "Secret/creds", State: primerv1alpha1.DriftStateNotInCluster},
+		{Object: "Service/web", State: primerv1alpha1.DriftStateNotInRepository},
+	}
+	if !reflect.DeepEqual(drift.Objects, want) {
+		t.Errorf("objects = %+v, want %+v", drift.Objects, want)
+	}
+	drifted := m.Status.Conditions.GetCondition(primerv1alpha1.ConditionDrifted)
+	if drifted == nil || drifted.Status != corev1.ConditionTrue || drifted.Reason != primerv1alpha1.DriftedReasonDetected || drifted.Message != "7 objects differ from main" {
+		t.Errorf("condition = %+v", drifted)
+	}
+	metrics := map[string]float64{
+		"drifted":    testutil.ToFloat64(driftedGauge.WithLabelValues("drift", "nightly")),
+		"modified":   testutil.ToFloat64(driftedObjectsGauge.WithLabelValues("drift", "nightly", string(primerv1alpha1.DriftStateModified))),
+		"not in git": testutil.ToFloat64(driftedObjectsGauge.WithLabelValues("drift", "nightly", string(primerv1alpha1.DriftStateNotInRepository))),
+		"checked":    testutil.ToFloat64(driftLastCheckGauge.WithLabelValues("drift", "nightly")),
+	}
+	if want := map[string]float64{"drifted": 1, "modified": 5, "not in git": 1, "checked": float64(checked.Unix())}; !reflect.DeepEqual(metrics, want) {
+		t.Errorf("metrics = %v, want %v", metrics, want)
+	}
+
+	// Back in sync
+	setDrift(m, &result.Diff{}, &checked)
+	if m.Status.Drift.Count != 0 || len(m.Status.Drift.Objects) != 0 {
+		t.Errorf("drift = %+v", m.Status.Drift)
+	}
+	drifted = m.Status.Conditions.GetCondition(primerv1alpha1.ConditionDrifted)
+	if drifted == nil || drifted.Status != corev1.ConditionFalse || drifted.Reason != primerv1alpha1.DriftedReasonInSync {
+		t.Errorf("condition = %+v", drifted)
+	}
+	if got := testutil.ToFloat64(driftedGauge.WithLabelValues("drift", "nightly")); got != 0 {
+		t.Errorf("drifted = %v", got)
+	}
+}
+
+func TestDeleteDriftMetrics(t *testing.T) {
+	m := gitExport("drift", "deleted", "git@example.com:org/gitops.git", 0)
+	checked := metav1.Now()
+	setDrift(m, &result.Diff{Added: []string{"Service/web"}}, &checked)
+	before := testutil.CollectAndCount(driftedObjectsGauge)
+
+	deleteDriftMetrics(m.Namespace, m.Name)
+	if got, want := testutil.CollectAndCount(driftedObjectsGauge), before-len(driftStates); got != want {
+		t.Errorf("%d drifted object series left, want %d", got, want)
+	}
+	if got := testutil.CollectAndCount(driftedGauge) + testutil.CollectAndCount(driftLastCheckGauge); got != 0 {
+		t.Errorf("%d series left", got)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/bootstrap"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// bootstrapManifests writes the manifests pointing a GitOps tool at the
+// export to a file or applies them to the cluster
+func bootstrapManifests(args []string) error {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	out := flags.String("out", "", "file to write the manifests to")
+	apply := flags.Bool("apply", false, "apply the manifests to the cluster as the user of the export")
+	flags.Parse(args)
+	if (*out == "") == !*apply {
+		return fmt.Errorf("one of -out and -apply is required")
+	}
+
+	tool := os.Getenv("BOOTSTRAP_TOOL")
+	namespace := os.Getenv("BOOTSTRAP_NAMESPACE")
+	if namespace == "" {
+		namespace = map[string]string{"argocd": "argocd", "flux": "flux-system"}[tool]
+	}
+	targetPath := os.Getenv("TARGET_PATH")
+	if targetPath == "" {
+		targetPath = os.Getenv("NAMESPACE")
+	}
+	objects, err := bootstrap.Manifests(tool, bootstrap.Options{
+		Name:            os.Getenv("NAMESPACE") + "-" + os.Getenv("EXPORT_NAME"),
+		Namespace:       namespace,
+		Repo:            os.Getenv("REPO"),
+		Branch:          os.Getenv("BRANCH"),
+		Path:            targetPath,
+		TargetNamespace: os.Getenv("NAMESPACE"),
+		Project:         os.Getenv("BOOTSTRAP_PROJECT"),
+		SecretRef:       os.Getenv("BOOTSTRAP_SECRET_REF"),
+	})
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		return bootstrap.Write(*out, objects)
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err = bootstrap.Apply(ctx, bootstrap.Cluster{
+		Server: "https://" + os.Getenv("KUBERNETES_SERVICE_HOST") + ":" + os.Getenv("KUBERNETES_SERVICE_PORT"),
+		Token:  strings.TrimSpace(string(token)),
+		CA:     ca,
+		User:   os.Getenv("USER"),
+	}, objects)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %s bootstrap manifests to namespace %s\n", tool, namespace)
+	return nil
+}
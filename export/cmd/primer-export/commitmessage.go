@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/commit"
+)
+
+// commitMessage prints the message for the changes staged in the
+// repository, rendering the subject from COMMIT_MESSAGE
+func commitMessage(args []string) error {
+	flags := flag.NewFlagSet("commit-message", flag.ExitOnError)
+	dir := flags.String("dir", ".", "repository holding the staged changes")
+	flags.Parse(args)
+
+	changes, err := commit.StagedChanges(*dir)
+	if err != nil {
+		return err
+	}
+	vars := commit.NewVars(os.Getenv("NAMESPACE"), os.Getenv("EXPORT_NAME"), os.Getenv("USER"), time.Now())
+	message, err := commit.Message(os.Getenv("COMMIT_MESSAGE"), vars, changes)
+	if err != nil {
+		return err
+	}
+	fmt.Print(message)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/commit"
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+)
+
+// diff records a summary of the changes staged in the repository in the
+// result, for dry runs and drift checks, and writes their unified diff
+func diff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	dir := flags.String("dir", ".", "repository holding the staged changes")
+	out := flags.String("out", "", "file the unified diff is written to, if any")
+	flags.Parse(args)
+
+	if *out != "" {
+		unified, err := commit.StagedDiff(*dir)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(*out, []byte(unified), 0644); err != nil {
+			return err
+		}
+	}
+	changes, err := commit.StagedChanges(*dir)
+	if err != nil {
+		return err
+	}
+	summary := &result.Diff{}
+	for _, change := range changes {
+		switch change.Status {
+		case "Added":
+			summary.Added = append(summary.Added, change.String())
+		case "Deleted":
+			summary.Removed = append(summary.Removed, change.String())
+		default:
+			summary.Changed = append(summary.Changed, change.String())
+		}
+	}
+	summary.AddedCount, summary.ChangedCount, summary.RemovedCount = len(summary.Added), len(summary.Changed), len(summary.Removed)
+	fmt.Printf("%d added, %d changed, %d removed\n", summary.AddedCount, summary.ChangedCount, summary.RemovedCount)
+	return result.Update(result.Path(), func(r *result.Result) {
+		r.Diff = summary
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/sops"
+)
+
+// encrypt encrypts the exported Secrets with SOPS for the age recipients
+// of the export
+func encrypt(args []string) error {
+	flags := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	previous := flags.String("previous", "", "directory holding the manifests of the previous export")
+	recipients := flags.String("recipients", os.Getenv("SOPS_AGE_RECIPIENTS"), "comma separated age recipients")
+	recipientsFile := flags.String("recipients-file", "/encryption/recipients", "file listing further age recipients, one per line")
+	identityFile := flags.String("identity-file", "/encryption/keys.txt", "age identities used to decrypt the previous export")
+	flags.Parse(args)
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+
+	all := sops.ParseRecipients(*recipients)
+	if data, err := ioutil.ReadFile(*recipientsFile); err == nil {
+		all = append(all, sops.ParseRecipients(string(data))...)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := os.Stat(*identityFile); err != nil {
+		*identityFile = ""
+	}
+
+	encrypted, err := sops.Encrypt(*dir, sops.Options{
+		Recipients:   all,
+		Previous:     *previous,
+		IdentityFile: *identityFile,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Encrypted %d Secrets, %d unchanged\n", len(encrypted.Encrypted), len(encrypted.Unchanged))
+	return nil
+}
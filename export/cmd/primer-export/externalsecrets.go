@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/externalsecrets"
+)
+
+// externalSecrets replaces the exported Secrets with ExternalSecrets
+func externalSecrets(args []string) error {
+	flags := flag.NewFlagSet("external-secrets", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	store := flags.String("store", os.Getenv("EXTERNAL_SECRETS_STORE"), "SecretStore the values are read from")
+	storeKind := flags.String("store-kind", os.Getenv("EXTERNAL_SECRETS_STORE_KIND"), "kind of the store, SecretStore or ClusterSecretStore")
+	keyTemplate := flags.String("key-template", os.Getenv("EXTERNAL_SECRETS_KEY_TEMPLATE"), "template of the key in the store")
+	refreshInterval := flags.String("refresh-interval", os.Getenv("EXTERNAL_SECRETS_REFRESH_INTERVAL"), "how often the values are read from the store")
+	namespace := flags.String("namespace", os.Getenv("NAMESPACE"), "namespace the Secrets were exported from")
+	flags.Parse(args)
+	if *dir == "" || *store == "" {
+		return fmt.Errorf("-dir and -store are required")
+	}
+
+	replaced, err := externalsecrets.Replace(*dir, externalsecrets.Options{
+		Store:           *store,
+		StoreKind:       *storeKind,
+		KeyTemplate:     *keyTemplate,
+		RefreshInterval: *refreshInterval,
+		Namespace:       *namespace,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replaced %d Secrets with ExternalSecrets\n", len(replaced))
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/helm"
+)
+
+// helmOutput turns the exported manifests into a Helm chart
+func helmOutput(args []string) error {
+	flags := flag.NewFlagSet("helm", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	name := flags.String("name", os.Getenv("EXPORT_NAME"), "name of the chart")
+	flags.Parse(args)
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+	return helm.Generate(*dir, helm.Options{
+		Name:        *name,
+		Description: "Exported from the " + os.Getenv("NAMESPACE") + " namespace by GitOps Primer",
+	})
+}
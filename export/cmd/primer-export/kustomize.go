@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/kustomize"
+)
+
+// kustomizeOutput writes a kustomization for the exported manifests
+func kustomizeOutput(args []string) error {
+	flags := flag.NewFlagSet("kustomize", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	generators := flags.Bool("generators", false, "replace ConfigMaps and Secrets with generators")
+	target := flags.String("target", "", "directory of the repository the manifests are synced to")
+	flags.Parse(args)
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+	return kustomize.Generate(*dir, kustomize.Options{Generators: *generators, Target: *target})
+}
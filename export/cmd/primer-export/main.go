@@ -0,0 +1,49 @@
+// primer-export holds the steps of the export job that are easier to write
+// in Go than in committer.sh. Each step is a subcommand configured through
+// the environment of the export job.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// commands maps a subcommand to the function that runs it
+var commands = map[string]func(args []string) error{
+	"bootstrap":        bootstrapManifests,
+	"commit-message":   commitMessage,
+	"diff":             diff,
+	"encrypt":          encrypt,
+	"external-secrets": externalSecrets,
+	"helm":             helmOutput,
+	"kustomize":        kustomizeOutput,
+	"pull-request":     pullRequest,
+	"redact":           redactSecrets,
+	"result":           recordResult,
+	"scan":             scanSecrets,
+	"seal":             seal,
+	"sync":             sync,
+	"watch":            watchNamespace,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintf(os.Stderr, "usage: %s <%s> [flags]\n", os.Args[0], strings.Join(commandNames(), "|"))
+		os.Exit(2)
+	}
+	if err := commands[os.Args[1]](os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func commandNames() []string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
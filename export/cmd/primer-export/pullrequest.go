@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/pullrequest"
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+)
+
+// pullRequest opens a pull request for a branch that has already been
+// pushed, or updates the one still open for it, and records it in the
+// result of the export
+func pullRequest(args []string) error {
+	flags := flag.NewFlagSet("pull-request", flag.ExitOnError)
+	head := flags.String("head", "", "branch holding the export")
+	base := flags.String("base", os.Getenv("BRANCH"), "branch to open the pull request against")
+	title := flags.String("title", "Export of "+os.Getenv("NAMESPACE"), "title of the pull request")
+	body := flags.String("body", "", "description of the pull request")
+	flags.Parse(args)
+	if *head == "" {
+		return fmt.Errorf("-head is required")
+	}
+
+	tokenFile := os.Getenv("PR_TOKEN_FILE")
+	if tokenFile == "" {
+		tokenFile = "/pull-request/token"
+	}
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return err
+	}
+
+	repoURL := os.Getenv("REPO")
+	provider, err := pullrequest.New(os.Getenv("PR_PROVIDER"), repoURL, pullrequest.Config{
+		APIURL: os.Getenv("PR_API_URL"),
+		Token:  strings.TrimSpace(string(token)),
+	})
+	if err != nil {
+		return err
+	}
+	repository := os.Getenv("PR_REPOSITORY")
+	if repository == "" {
+		if _, repository, err = pullrequest.ParseRepoURL(repoURL); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	pr, opened, err := pullrequest.OpenOrUpdate(ctx, provider, pullrequest.Request{
+		Repository: repository,
+		Head:       *head,
+		Base:       *base,
+		Title:      *title,
+		Body:       *body,
+	})
+	if err != nil {
+		return err
+	}
+	if opened {
+		fmt.Printf("Opened pull request %d %s\n", pr.Number, pr.URL)
+	} else {
+		fmt.Printf("Updated pull request %d %s\n", pr.Number, pr.URL)
+	}
+	return result.Update(result.Path(), func(r *result.Result) {
+		r.PullRequestURL = pr.URL
+		r.PullRequestNumber = pr.Number
+	})
+}
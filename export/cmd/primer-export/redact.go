@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/redact"
+)
+
+// redactSecrets replaces the values of the exported Secrets with
+// placeholders and writes the checklist of the values to fill in
+func redactSecrets(args []string) error {
+	flags := flag.NewFlagSet("redact", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	namespace := flags.String("namespace", os.Getenv("NAMESPACE"), "namespace the Secrets were exported from")
+	flags.Parse(args)
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+
+	secrets, err := redact.Redact(*dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Redacted %d Secrets\n", len(secrets))
+	if len(secrets) == 0 {
+		return nil
+	}
+	return ioutil.WriteFile(filepath.Join(*dir, redact.ChecklistFile), redact.Checklist(*namespace, secrets), 0644)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+)
+
+// recordResult adds values worked out by committer.sh to the result of
+// the export
+func recordResult(args []string) error {
+	flags := flag.NewFlagSet("result", flag.ExitOnError)
+	fingerprint := flags.String("signing-key-fingerprint", "", "fingerprint of the key commits are signed with")
+	head := flags.String("pull-request-head", "", "commit pushed to the branch of the pull request")
+	flags.Parse(args)
+
+	return result.Update(result.Path(), func(r *result.Result) {
+		if *fingerprint != "" {
+			r.SigningKeyFingerprint = *fingerprint
+		}
+		if *head != "" {
+			r.PullRequestHead = *head
+		}
+	})
+}
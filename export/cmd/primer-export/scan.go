@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+	"github.com/cooktheryan/gitops-primer/export/pkg/scan"
+)
+
+// scanSecrets checks the exported manifests for credentials. Depending on
+// the policy findings are redacted or recorded in the result as
+// SecretsDetected, which fails the export
+func scanSecrets(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	policy := flags.String("policy", os.Getenv("SECRET_SCANNING_POLICY"), "Fail or Redact")
+	patterns := flags.String("patterns", os.Getenv("SECRET_SCANNING_PATTERNS"), "JSON list of additional patterns with a name and regex")
+	flags.Parse(args)
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+
+	extra := []scan.Pattern{}
+	if *patterns != "" {
+		if err := json.Unmarshal([]byte(*patterns), &extra); err != nil {
+			return fmt.Errorf("invalid patterns: %v", err)
+		}
+	}
+	scanner, err := scan.New(extra)
+	if err != nil {
+		return err
+	}
+	redactFindings := *policy == "Redact"
+	findings, err := scanner.Dir(*dir, redactFindings)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		fmt.Println("No secrets detected")
+		return nil
+	}
+
+	summary := []string{}
+	for _, f := range findings {
+		if redactFindings {
+			fmt.Printf("Redacted %s\n", f)
+		} else {
+			fmt.Printf("Secret detected in %s\n", f)
+		}
+		summary = append(summary, f.String())
+	}
+
+	if redactFindings {
+		return result.Update(result.Path(), func(r *result.Result) {
+			r.SecretsRedacted = summary
+		})
+	}
+	// Failing the export stage would only see the Job retried with the same
+	// findings, instead committer.sh stops once they are in the result
+	return result.Update(result.Path(), func(r *result.Result) {
+		r.SecretsDetected = summary
+	})
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/sealedsecrets"
+)
+
+// seal replaces the exported Secrets with SealedSecrets
+func seal(args []string) error {
+	flags := flag.NewFlagSet("seal", flag.ExitOnError)
+	dir := flags.String("dir", "", "directory holding the exported manifests")
+	cert := flags.String("cert", os.Getenv("SEALED_SECRETS_CERT"), "certificate of the sealed-secrets controller")
+	namespace := flags.String("namespace", os.Getenv("NAMESPACE"), "namespace the Secrets were exported from")
+	previous := flags.String("previous", "", "directory holding the previous export, unchanged Secrets keep their SealedSecret")
+	flags.Parse(args)
+	if *dir == "" || *cert == "" {
+		return fmt.Errorf("-dir and -cert are required")
+	}
+
+	sealed, err := sealedsecrets.Seal(*dir, sealedsecrets.Options{
+		Certificate: *cert,
+		Namespace:   *namespace,
+		Previous:    *previous,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Sealed %d Secrets, %d unchanged\n", len(sealed.Sealed), len(sealed.Unchanged))
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/mirror"
+	"github.com/cooktheryan/gitops-primer/export/pkg/result"
+)
+
+// sync writes the exported manifests into the target directory of the
+// repository and prunes the files of objects that are no longer exported
+func sync(args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	from := flags.String("from", "", "directory holding the exported manifests")
+	to := flags.String("to", "", "directory within the repository to write to")
+	merge := flags.Bool("merge", false, "merge changes made in the repository with the export")
+	base := flags.String("base", "", "directory the merge bases are kept in, required by -merge")
+	flags.Parse(args)
+	if *from == "" || *to == "" {
+		return fmt.Errorf("-from and -to are required")
+	}
+
+	synced, err := mirror.Sync(*from, *to, mirror.Options{Merge: *merge, Base: *base})
+	if err != nil {
+		return err
+	}
+	for _, file := range synced.Pruned {
+		fmt.Printf("Pruned %s\n", file)
+	}
+	for _, file := range synced.Ignored {
+		fmt.Printf("Ignored %s\n", file)
+	}
+	for _, file := range synced.Conflicts {
+		fmt.Printf("Conflict in %s, left unchanged\n", file)
+	}
+	fmt.Printf("Wrote %d files, pruned %d files\n", len(synced.Written), len(synced.Pruned))
+	return result.Update(result.Path(), func(r *result.Result) {
+		r.MergeConflicts = synced.Conflicts
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/watch"
+)
+
+// watchNamespace runs the export command once and then again each time
+// objects in the namespace change, for continuous exports. The command is
+// given after the flags
+func watchNamespace(args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	debounce := flags.Duration("debounce", 10*time.Second, "time without changes to wait for before exporting")
+	maxDelay := flags.Duration("max-delay", time.Minute, "longest time to hold back a change while the namespace keeps changing")
+	flags.Parse(args)
+	command := flags.Args()
+	if len(command) == 0 {
+		return fmt.Errorf("a command to run on changes is required")
+	}
+	if *debounce <= 0 || *maxDelay < *debounce {
+		return fmt.Errorf("-debounce must be positive and no longer than -max-delay")
+	}
+
+	// Objects changed before the watch starts are picked up by the first
+	// export, failing here restarts the container rather than leaving it
+	// watching an export that cannot succeed
+	if err := run(command, nil); err != nil {
+		return err
+	}
+
+	// Only the objects the user of the export can see are watched
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	config.Impersonate.UserName = os.Getenv("USER")
+	// Only the objects selected for the export set it off, the selection
+	// is the one handed to the ResourceSelectionPlugin
+	opts := watch.Options{
+		Namespace:     os.Getenv("NAMESPACE"),
+		Exclude:       splitList(os.Getenv("EXCLUDED_KINDS")),
+		Include:       splitList(os.Getenv("INCLUDED_KINDS")),
+		LabelSelector: os.Getenv("LABEL_SELECTOR"),
+		Objects:       splitList(os.Getenv("OBJECTS")),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := watch.NewChanges()
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- watch.Watch(ctx, config, opts, changes)
+		cancel()
+	}()
+	watch.Debounce(ctx, changes, *debounce, *maxDelay, func(objects []string) error {
+		return run(command, objects)
+	})
+	return <-watchErr
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// run runs the export command, logging the objects that set it off
+func run(command []string, objects []string) error {
+	if len(objects) != 0 {
+		fmt.Printf("Exporting changes to %s\n", strings.Join(objects, ", "))
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -1,13 +1,123 @@
 #!/bin/bash
 set -e
 
-if [ ${METHOD} == "git" ]; then 
+# The export job runs this script twice. The export stage gathers the
+# objects from the cluster and the push stage commits them to git or
+# packages them for download. Running without a stage does both, which is
+# how continuous exports run it each time the namespace changes.
+STAGE=${1:-all}
+
+# Directory within the repository the export is written to, exports
+# sharing a repository each write to a directory of their own
+TARGET_PATH=${TARGET_PATH:-${NAMESPACE}}
+
+# Paths committed by the export, the bootstrap manifests are kept outside
+# of the export so that a GitOps tool syncing it does not manage itself
+COMMIT_PATHS=("${TARGET_PATH}")
+if [ "${MERGE_STRATEGY}" == "ThreeWay" ]; then
+  # The merge bases are kept at the root of the repository so that they
+  # are not synced to the cluster along with the export
+  BASE_PATH=.primer-base/${TARGET_PATH}
+  COMMIT_PATHS+=("${BASE_PATH}")
+fi
+if [ "${BOOTSTRAP_MODE}" == "Commit" ]; then
+  BOOTSTRAP_PATH=${BOOTSTRAP_PATH:-bootstrap/${NAMESPACE}-${EXPORT_NAME}.yaml}
+  COMMIT_PATHS+=("${BOOTSTRAP_PATH}")
+fi
+
+# Both stages add to the result of the export, which is handed to the
+# controller as the termination message of the push stage
+export RESULT_PATH=~/primer-result.json
+if [ ${STAGE} != "push" ]; then
+  rm -f ${RESULT_PATH}
+fi
+if [ ${STAGE} != "export" ]; then
+  trap 'if [ $? -eq 0 ] && [ -f ${RESULT_PATH} ]; then cp ${RESULT_PATH} /dev/termination-log; fi' EXIT
+fi
+
+if [ ${STAGE} != "push" ]; then
+
+# Percent-encode a value for use in a URL
+urlencode() {
+  local value="$1" encoded="" c i
+  for (( i=0; i<${#value}; i++ )); do
+    c=${value:i:1}
+    case ${c} in
+      [a-zA-Z0-9.~_-]) encoded+=${c} ;;
+      *) encoded+=$(printf '%%%02X' "'${c}") ;;
+    esac
+  done
+  echo "${encoded}"
+}
+
+if [ ${METHOD} == "git" ] && [ -d /credentials ]; then
+  # Setup HTTPS credentials, either a .git-credentials file or a
+  # username and password or token for the host of the repository
+  if [ -f /credentials/.git-credentials ]; then
+    cp /credentials/.git-credentials ~/.git-credentials
+  else
+    GIT_USERNAME=git
+    if [ -f /credentials/username ]; then
+      GIT_USERNAME=$(cat /credentials/username)
+    fi
+    if [ -f /credentials/token ]; then
+      GIT_PASSWORD=$(cat /credentials/token)
+    else
+      GIT_PASSWORD=$(cat /credentials/password)
+    fi
+    GIT_HOST=$(echo ${REPO} | sed -E 's#^https://([^@/]*@)?([^/]+).*#\2#')
+    echo "https://$(urlencode "${GIT_USERNAME}"):$(urlencode "${GIT_PASSWORD}")@${GIT_HOST}" > ~/.git-credentials
+  fi
+  chmod 0600 ~/.git-credentials
+  git config --global credential.helper store
+elif [ ${METHOD} == "git" ]; then
   # Setup SSH
   mkdir -p ~/.ssh/controlmasters
   chmod 711 ~/.ssh
-  cp /keys/id_rsa ~/.ssh/id_rsa
-  chmod 0600 ~/.ssh/id_rsa
-  ssh-keyscan -t rsa github.com >> ~/.ssh/known_hosts
+
+  # Use the named key, or the first of the usual key names in the Secret
+  if [ -z "${SSH_KEY_NAME}" ]; then
+    for name in ssh-privatekey id_ed25519 id_ecdsa id_rsa; do
+      if [ -f /keys/${name} ]; then
+        SSH_KEY_NAME=${name}
+        break
+      fi
+    done
+  fi
+  if [ ! -f "/keys/${SSH_KEY_NAME}" ]; then
+    echo "No SSH key found in the secret"
+    exit 1
+  fi
+  cp /keys/${SSH_KEY_NAME} ~/.ssh/id
+  chmod 0600 ~/.ssh/id
+  if [ -f /keys/passphrase ]; then
+    ssh-keygen -p -q -P "$(cat /keys/passphrase)" -N "" -f ~/.ssh/id > /dev/null
+  fi
+
+  # Work out the host and port of the repository from either
+  # ssh://[user@]host[:port]/path or [user@]host:path
+  if [[ ${REPO} =~ ^ssh://([^@/]+@)?([^:/]+)(:([0-9]+))?/ ]]; then
+    GIT_HOST=${BASH_REMATCH[2]}
+    GIT_PORT=${BASH_REMATCH[4]:-22}
+  elif [[ ${REPO} =~ ^([^@/]+@)?([^:/]+): ]]; then
+    GIT_HOST=${BASH_REMATCH[2]}
+    GIT_PORT=22
+  fi
+
+  # Host keys are verified against the known_hosts given on the Export
+  # or in the Secret. Without either the keys are only trusted on first
+  # use when the Export opts in to it
+  if [ -n "${KNOWN_HOSTS}" ]; then
+    echo "${KNOWN_HOSTS}" > ~/.ssh/known_hosts
+  elif [ -f /keys/known_hosts ]; then
+    cp /keys/known_hosts ~/.ssh/known_hosts
+  elif [ "${INSECURE_ACCEPT_HOST_KEYS}" == "true" ]; then
+    echo "WARNING: no known_hosts provided, trusting the host keys of ${GIT_HOST}"
+    ssh-keyscan -p ${GIT_PORT} ${GIT_HOST} >> ~/.ssh/known_hosts
+  else
+    echo "ERROR: no known_hosts provided for ${GIT_HOST}, set knownHosts on the Export or add a known_hosts key to the Secret"
+    exit 1
+  fi
 
 cat - <<SSHCONFIG > ~/.ssh/config
 Host *
@@ -17,28 +127,73 @@ Host *
   ControlMaster auto
   ControlPath ~/.ssh/controlmasters/%C
   ControlPersist 5
-  # Disables warning when IP is added to known_hosts
+  # Only connect to hosts listed in known_hosts
+  StrictHostKeyChecking yes
+  UserKnownHostsFile ~/.ssh/known_hosts
+  # Host keys are checked by name rather than IP
   CheckHostIP no
   # Use the identity provided via attached Secret
-  IdentityFile /keys/id_rsa
+  IdentityFile ~/.ssh/id
+  IdentitiesOnly yes
   # Enable protocol-level keepalive to detect connection failure
   ServerAliveCountMax 4
   ServerAliveInterval 30
   # Using protocol-level, so we don't need TCP-level
   TCPKeepAlive no
 SSHCONFIG
+fi
 
-  # Setup the repository
+if [ ${METHOD} == "git" ]; then
+  # Setup the repository, starting from a fresh clone on every run
+  rm -rf /output/repo
   git clone ${REPO} /output/repo -q
   cd /output/repo
   git fetch -q 
   existed_in_remote=$(git ls-remote --heads origin ${BRANCH})
+  if [[ -z ${existed_in_remote} ]] && [ -n "${PR_PROVIDER}" ]; then
+     echo "ERROR: branch ${BRANCH} does not exist in ${REPO}, create it to open pull requests against it"
+     exit 1
+  fi
   if [[ -z ${existed_in_remote} ]]; then
      git checkout -b ${BRANCH}
   else  
      git checkout ${BRANCH}
   fi
   git config --global user.email "${EMAIL}"
+  git config --global user.name "${AUTHOR_NAME:-GitOps Primer}"
+fi
+
+if [ ${METHOD} == "git" ] && [ -d /signing ]; then
+  # Sign every commit and tag with the key from the signing Secret
+  if [ ${SIGNING_FORMAT} == "ssh" ]; then
+    mkdir -p ~/.ssh
+    cp /signing/key ~/.ssh/signing_key
+    chmod 0600 ~/.ssh/signing_key
+    if [ -f /signing/passphrase ]; then
+      ssh-keygen -p -q -P "$(cat /signing/passphrase)" -N "" -f ~/.ssh/signing_key > /dev/null
+    fi
+    git config --global gpg.format ssh
+    git config --global user.signingkey ~/.ssh/signing_key
+    SIGNING_KEY_FINGERPRINT=$(ssh-keygen -l -f ~/.ssh/signing_key | awk '{print $2}')
+  else
+    GPG_OPTS="--batch --pinentry-mode loopback"
+    if [ -f /signing/passphrase ]; then
+      GPG_OPTS="${GPG_OPTS} --passphrase-file /signing/passphrase"
+    fi
+    gpg ${GPG_OPTS} --import /signing/key
+    # git runs gpg without the options needed to unlock the key
+    cat - <<GPGPROGRAM > ~/gpg-sign
+#!/bin/bash
+exec gpg ${GPG_OPTS} "\$@"
+GPGPROGRAM
+    chmod 0700 ~/gpg-sign
+    git config --global gpg.program ~/gpg-sign
+    SIGNING_KEY_FINGERPRINT=$(gpg --with-colons --list-secret-keys | awk -F: '/^fpr/ {print $10; exit}')
+    git config --global user.signingkey ${SIGNING_KEY_FINGERPRINT}
+  fi
+  git config --global commit.gpgsign true
+  git config --global tag.gpgsign true
+  primer-export result -signing-key-fingerprint "${SIGNING_KEY_FINGERPRINT}"
 fi
 
 TOKEN=`cat /var/run/secrets/kubernetes.io/serviceaccount/token`
@@ -72,19 +227,142 @@ if [ ${METHOD} == "download" ]; then
 fi
 
 export KUBECONFIG=/tmp/kubeconfig
+rm -rf /tmp/export /tmp/transform /tmp/apply
 crane export --export-dir /tmp/export --as-user ${USER}
 crane transform --export-dir /tmp/export/resources --plugin-dir /opt --transform-dir /tmp/transform --skip-plugins KubernetesPlugin
-crane apply --export-dir /tmp/export/resources --transform-dir /tmp/transform --output-dir /output/repo
+crane apply --export-dir /tmp/export/resources --transform-dir /tmp/transform --output-dir /tmp/apply
+mkdir -p /tmp/apply/${NAMESPACE}
 
+# Seal, replace or redact Secrets before the output format is applied so
+# that a kustomization or chart picks up the replacements
+if [ "${ENCRYPTION}" == "sealedsecrets" ]; then
+  # Unchanged Secrets keep the SealedSecret committed by the last export,
+  # which a chart keeps with its templates
+  PREVIOUS_PATH="/output/repo/${TARGET_PATH}"
+  if [ "${FORMAT}" == "helm" ]; then
+    PREVIOUS_PATH="${PREVIOUS_PATH}/templates"
+  fi
+  primer-export seal -dir /tmp/apply/${NAMESPACE} -previous "${PREVIOUS_PATH}"
+fi
+if [ -n "${EXTERNAL_SECRETS_STORE}" ]; then
+  primer-export external-secrets -dir /tmp/apply/${NAMESPACE}
+fi
+if [ "${REDACT_SECRETS}" == "true" ]; then
+  primer-export redact -dir /tmp/apply/${NAMESPACE}
+fi
 
-if [ ${METHOD} == "git" ]; then 
-  if [[ $(git status -s) ]]; then
-     git add *
-     git commit -am 'bot commit'
-     git push origin ${BRANCH} -q
-     echo "Merge to ${BRANCH} completed successfully"
-  else
-     exit 0
+# Check for credentials left outside of Secrets before anything is
+# written to the repository
+if [ -n "${SECRET_SCANNING_POLICY}" ]; then
+  primer-export scan -dir /tmp/apply/${NAMESPACE}
+fi
+# Possible secrets found with the Fail policy end the export. The export
+# job finishes without being retried and hands them to the controller in
+# the result, continuous exports try again with the next change
+if grep -qs '"secretsDetected"' ${RESULT_PATH}; then
+  echo "Possible secrets detected, not committing the export"
+  if [ ${STAGE} == "all" ]; then
+    exit 1
+  fi
+  exit 0
+fi
+
+# Turn the manifests into the requested output format
+if [ "${FORMAT}" == "kustomize" ]; then
+  KUSTOMIZE_OPTS=()
+  if [ "${GENERATORS}" == "true" ]; then
+    KUSTOMIZE_OPTS+=(-generators)
+  fi
+  if [ ${METHOD} == "git" ]; then
+    KUSTOMIZE_OPTS+=(-target "/output/repo/${TARGET_PATH}")
+  fi
+  primer-export kustomize "${KUSTOMIZE_OPTS[@]}" -dir /tmp/apply/${NAMESPACE}
+elif [ "${FORMAT}" == "helm" ]; then
+  primer-export helm -dir /tmp/apply/${NAMESPACE}
+fi
+
+# Encrypt Secrets so that they never reach the repository in plain text
+if [ "${ENCRYPTION}" == "sops" ]; then
+  primer-export encrypt -dir /tmp/apply/${NAMESPACE} -previous "/output/repo/${TARGET_PATH}"
+fi
+
+if [ ${METHOD} == "git" ]; then
+  SYNC_OPTS=()
+  if [ "${MERGE_STRATEGY}" == "ThreeWay" ]; then
+    SYNC_OPTS=(-merge -base "/output/repo/${BASE_PATH}")
+  fi
+  primer-export sync "${SYNC_OPTS[@]}" -from /tmp/apply/${NAMESPACE} -to "/output/repo/${TARGET_PATH}"
+  if [ "${BOOTSTRAP_MODE}" == "Commit" ]; then
+    primer-export bootstrap -out "/output/repo/${BOOTSTRAP_PATH}"
+  fi
+else
+  mkdir -p /output/repo/${NAMESPACE}
+  cp -r /tmp/apply/${NAMESPACE}/. /output/repo/${NAMESPACE}/
+fi
+
+fi
+
+if [ ${STAGE} == "export" ]; then
+  exit 0
+fi
+if grep -qs '"secretsDetected"' ${RESULT_PATH}; then
+  exit 0
+fi
+
+if [ ${METHOD} == "git" ] && [ "${DRY_RUN}" == "true" ]; then
+  # Work out what would be committed, without committing or pushing it,
+  # and leave the diff for download in place of the repository
+  cd /output/repo
+  git add -A -- "${COMMIT_PATHS[@]}"
+  primer-export diff -out /output/${NAMESPACE}-${TIME}.diff
+  cd /output
+  rm -rf /output/repo
+elif [ ${METHOD} == "git" ] && [ "${DRIFT_CHECK}" == "true" ]; then
+  # Compare the cluster with the repository, leaving both unchanged
+  cd /output/repo
+  git add -A -- "${COMMIT_PATHS[@]}"
+  primer-export diff
+elif [ ${METHOD} == "git" ]; then 
+  cd /output/repo
+  if [[ $(git status -s -- "${COMMIT_PATHS[@]}") ]]; then
+     git add -A -- "${COMMIT_PATHS[@]}"
+     primer-export commit-message > /tmp/commit-message
+     git commit -q -F /tmp/commit-message
+     if [ -n "${PR_PROVIDER}" ]; then
+       # Push to a branch of its own and ask for it to be merged. The
+       # branch is replaced by each export, updating the pull request
+       # while it is open
+       PR_BRANCH=primer-export/${NAMESPACE}/${EXPORT_NAME}
+       # Only replace the branch while it holds what the last export
+       # pushed, leaving commits added to it by reviewers in place
+       PR_REMOTE_HEAD=$(git ls-remote origin refs/heads/${PR_BRANCH} | cut -f1)
+       if [ -n "${PR_REMOTE_HEAD}" ] && [ "${PR_REMOTE_HEAD}" != "${PR_HEAD}" ]; then
+         echo "ERROR: branch ${PR_BRANCH} has commits not pushed by the export, merge or delete it to export again"
+         exit 1
+       fi
+       git push --force-with-lease=refs/heads/${PR_BRANCH}:${PR_REMOTE_HEAD} origin HEAD:refs/heads/${PR_BRANCH} -q
+       primer-export result -pull-request-head "$(git rev-parse HEAD)"
+       primer-export pull-request -head ${PR_BRANCH} -base ${BRANCH} \
+         -title "$(git log -1 --format=%s)" -body "$(git log -1 --format=%b)"
+     else
+       # Other exports may push to the same branch, replay the commit on
+       # top of theirs when the push is rejected
+       for attempt in 1 2 3 4 5; do
+         if git push origin ${BRANCH} -q; then
+           echo "Merge to ${BRANCH} completed successfully"
+           break
+         fi
+         if [ ${attempt} == 5 ]; then
+           echo "Unable to push to ${BRANCH}"
+           exit 1
+         fi
+         sleep $((attempt * 2))
+         git pull --rebase -q origin ${BRANCH}
+       done
+     fi
+  fi
+  if [ "${BOOTSTRAP_MODE}" == "Apply" ]; then
+     primer-export bootstrap -apply
   fi
 else
   cd /output/repo
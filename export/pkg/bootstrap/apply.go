@@ -0,0 +1,101 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// resources maps the kinds generated by this package to their resource
+var resources = map[string]string{
+	"Application":   "applications",
+	"GitRepository": "gitrepositories",
+	"Kustomization": "kustomizations",
+}
+
+// FieldManager owns the fields of the objects applied by Apply
+const FieldManager = "gitops-primer"
+
+// Cluster is the API server the objects are applied to
+type Cluster struct {
+	// URL of the API server
+	Server string
+	// Bearer token used to authenticate
+	Token string
+	// PEM encoded certificate authority of the API server
+	CA []byte
+	// User to impersonate, so that the objects can only be created by
+	// someone allowed to
+	User string
+	// Client used for API requests, defaults to a client trusting CA
+	Client *http.Client
+}
+
+// Apply creates or updates the objects in the cluster using server-side
+// apply
+func Apply(ctx context.Context, cluster Cluster, objects []map[string]interface{}) error {
+	client := cluster.Client
+	if client == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cluster.CA) {
+			return fmt.Errorf("no certificates found in the certificate authority")
+		}
+		client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	}
+	for _, o := range objects {
+		if err := apply(ctx, client, cluster, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(ctx context.Context, client *http.Client, cluster Cluster, o map[string]interface{}) error {
+	kind, _ := o["kind"].(string)
+	apiVersion, _ := o["apiVersion"].(string)
+	meta, _ := o["metadata"].(map[string]interface{})
+	name, _ := meta["name"].(string)
+	namespace, _ := meta["namespace"].(string)
+	resource, ok := resources[kind]
+	if !ok {
+		return fmt.Errorf("unable to apply unknown kind %q", kind)
+	}
+
+	url := fmt.Sprintf("%s/apis/%s/namespaces/%s/%s/%s?fieldManager=%s&force=true",
+		strings.TrimSuffix(cluster.Server, "/"), apiVersion, namespace, resource, name, FieldManager)
+	body, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	// JSON is valid YAML, so the object can be sent as an apply patch as is
+	req.Header.Set("Content-Type", "application/apply-patch+yaml")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cluster.Token)
+	if cluster.User != "" {
+		req.Header.Set("Impersonate-User", cluster.User)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("apply %s %s/%s: %s: %s", kind, namespace, name, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
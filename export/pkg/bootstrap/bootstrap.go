@@ -0,0 +1,148 @@
+// Package bootstrap generates the manifests that point a GitOps tool at an
+// export, an Argo CD Application or a Flux GitRepository and Kustomization,
+// so that the repository can be synced back to the cluster straight away.
+package bootstrap
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Options describe the export the manifests point at
+type Options struct {
+	// Name of the generated objects
+	Name string
+	// Namespace the generated objects are created in
+	Namespace string
+	// Repository and branch the export is pushed to
+	Repo   string
+	Branch string
+	// Path of the export within the repository
+	Path string
+	// Namespace the objects of the export are synced to
+	TargetNamespace string
+	// Project of the Argo CD Application
+	Project string
+	// Secret Flux reads the git credentials from
+	SecretRef string
+}
+
+// generator returns the manifests for a tool
+type generator func(Options) []map[string]interface{}
+
+var generators = map[string]generator{
+	"argocd": argoCD,
+	"flux":   flux,
+}
+
+// Tools returns the names of the supported tools
+func Tools() []string {
+	tools := []string{}
+	for tool := range generators {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+// Manifests returns the objects pointing tool at the export
+func Manifests(tool string, opts Options) ([]map[string]interface{}, error) {
+	g, ok := generators[tool]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q, expected one of %s", tool, strings.Join(Tools(), ", "))
+	}
+	return g(opts), nil
+}
+
+// Write writes the objects to file as a multi-document YAML stream
+func Write(file string, objects []map[string]interface{}) error {
+	var out bytes.Buffer
+	for i, o := range objects {
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		data, err := yaml.Marshal(o)
+		if err != nil {
+			return err
+		}
+		out.Write(data)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, out.Bytes(), 0644)
+}
+
+func metadata(opts Options) map[string]interface{} {
+	return map[string]interface{}{
+		"name":      opts.Name,
+		"namespace": opts.Namespace,
+		"labels": map[string]interface{}{
+			"app.kubernetes.io/managed-by": "gitops-primer",
+		},
+	}
+}
+
+func argoCD(opts Options) []map[string]interface{} {
+	project := opts.Project
+	if project == "" {
+		project = "default"
+	}
+	return []map[string]interface{}{{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   metadata(opts),
+		"spec": map[string]interface{}{
+			"project": project,
+			"source": map[string]interface{}{
+				"repoURL":        opts.Repo,
+				"targetRevision": opts.Branch,
+				"path":           opts.Path,
+			},
+			"destination": map[string]interface{}{
+				"server":    "https://kubernetes.default.svc",
+				"namespace": opts.TargetNamespace,
+			},
+		},
+	}}
+}
+
+func flux(opts Options) []map[string]interface{} {
+	source := map[string]interface{}{
+		"interval": "1m",
+		"url":      opts.Repo,
+		"ref": map[string]interface{}{
+			"branch": opts.Branch,
+		},
+	}
+	if opts.SecretRef != "" {
+		source["secretRef"] = map[string]interface{}{"name": opts.SecretRef}
+	}
+	return []map[string]interface{}{{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "GitRepository",
+		"metadata":   metadata(opts),
+		"spec":       source,
+	}, {
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata":   metadata(opts),
+		"spec": map[string]interface{}{
+			"interval":        "10m",
+			"path":            "./" + strings.TrimPrefix(opts.Path, "./"),
+			"prune":           true,
+			"targetNamespace": opts.TargetNamespace,
+			"sourceRef": map[string]interface{}{
+				"kind": "GitRepository",
+				"name": opts.Name,
+			},
+		},
+	}}
+}
@@ -0,0 +1,189 @@
+package bootstrap
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var demo = Options{
+	Name:            "demo-export",
+	Namespace:       "gitops",
+	Repo:            "git@example.com:org/gitops.git",
+	Branch:          "main",
+	Path:            "demo/export",
+	TargetNamespace: "demo",
+}
+
+// field returns the value at path within o
+func field(o map[string]interface{}, path ...string) interface{} {
+	var v interface{} = o
+	for _, key := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func manifests(t *testing.T, tool string, opts Options) []map[string]interface{} {
+	t.Helper()
+	objects, err := Manifests(tool, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func TestArgoCD(t *testing.T) {
+	objects := manifests(t, "argocd", demo)
+	if len(objects) != 1 || objects[0]["kind"] != "Application" {
+		t.Fatalf("objects = %v, want an Application", objects)
+	}
+	app := objects[0]
+	for path, want := range map[string]interface{}{
+		"metadata.name":              "demo-export",
+		"metadata.namespace":         "gitops",
+		"spec.project":               "default",
+		"spec.source.repoURL":        "git@example.com:org/gitops.git",
+		"spec.source.targetRevision": "main",
+		"spec.source.path":           "demo/export",
+		"spec.destination.server":    "https://kubernetes.default.svc",
+		"spec.destination.namespace": "demo",
+	} {
+		if got := field(app, strings.Split(path, ".")...); got != want {
+			t.Errorf("%s = %v, want %v", path, got, want)
+		}
+	}
+	if got := field(app, "metadata", "labels", "app.kubernetes.io/managed-by"); got != "gitops-primer" {
+		t.Errorf("managed-by label = %v", got)
+	}
+
+	opts := demo
+	opts.Project = "team-a"
+	if got := field(manifests(t, "argocd", opts)[0], "spec", "project"); got != "team-a" {
+		t.Errorf("project = %v, want team-a", got)
+	}
+}
+
+func TestFlux(t *testing.T) {
+	opts := demo
+	opts.SecretRef = "git-credentials"
+	objects := manifests(t, "flux", opts)
+	if len(objects) != 2 || objects[0]["kind"] != "GitRepository" || objects[1]["kind"] != "Kustomization" {
+		t.Fatalf("objects = %v, want a GitRepository and a Kustomization", objects)
+	}
+	source, kustomization := objects[0], objects[1]
+	for _, o := range objects {
+		if field(o, "metadata", "name") != "demo-export" || field(o, "metadata", "namespace") != "gitops" {
+			t.Errorf("%s metadata = %v", o["kind"], o["metadata"])
+		}
+		if field(o, "metadata", "labels", "app.kubernetes.io/managed-by") != "gitops-primer" {
+			t.Errorf("%s labels = %v", o["kind"], field(o, "metadata", "labels"))
+		}
+	}
+	if field(source, "spec", "url") != opts.Repo || field(source, "spec", "ref", "branch") != "main" ||
+		field(source, "spec", "secretRef", "name") != "git-credentials" {
+		t.Errorf("GitRepository spec = %v", source["spec"])
+	}
+	if field(kustomization, "spec", "targetNamespace") != "demo" || field(kustomization, "spec", "prune") != true {
+		t.Errorf("Kustomization spec = %v", kustomization["spec"])
+	}
+	want := map[string]interface{}{"kind": "GitRepository", "name": "demo-export"}
+	if got := field(kustomization, "spec", "sourceRef"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sourceRef = %v, want %v", got, want)
+	}
+
+	// Flux paths are relative to the root of the repository
+	for _, path := range []string{"demo/export", "./demo/export"} {
+		opts.Path = path
+		if got := field(manifests(t, "flux", opts)[1], "spec", "path"); got != "./demo/export" {
+			t.Errorf("path %q = %v, want ./demo/export", path, got)
+		}
+	}
+	// Public repositories need no credentials
+	if got := field(manifests(t, "flux", demo)[0], "spec", "secretRef"); got != nil {
+		t.Errorf("secretRef = %v, want none", got)
+	}
+}
+
+func TestManifestsUnknownTool(t *testing.T) {
+	if _, err := Manifests("jenkins", demo); err == nil || !strings.Contains(err.Error(), "argocd, flux") {
+		t.Errorf("err = %v, want the supported tools listed", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bootstrap", "demo-export.yaml")
+	write := func(objects []map[string]interface{}) string {
+		t.Helper()
+		if err := Write(file, objects); err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	flux := write(manifests(t, "flux", demo))
+	if docs := strings.Split(flux, "---\n"); len(docs) != 2 || !strings.Contains(docs[0], "kind: GitRepository") || !strings.Contains(docs[1], "kind: Kustomization") {
+		t.Errorf("written =\n%s\nwant a GitRepository and a Kustomization", flux)
+	}
+	// Each export rewrites the file the same way, leaving nothing to commit
+	if again := write(manifests(t, "flux", demo)); again != flux {
+		t.Errorf("rewritten =\n%s\nwant\n%s", again, flux)
+	}
+	// and replaces what was there before
+	if argo := write(manifests(t, "argocd", demo)); strings.Contains(argo, "---") || strings.Contains(argo, "GitRepository") {
+		t.Errorf("written over flux =\n%s", argo)
+	}
+}
+
+func TestApply(t *testing.T) {
+	requests := []*http.Request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if strings.Contains(r.URL.Path, "/kustomizations/") {
+			http.Error(w, `{"message":"forbidden"}`, http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	cluster := Cluster{Server: server.URL + "/", Token: "token", User: "alice", Client: server.Client()}
+
+	err := Apply(context.TODO(), cluster, manifests(t, "flux", demo))
+	if err == nil || !strings.Contains(err.Error(), "apply Kustomization gitops/demo-export: 403 Forbidden") {
+		t.Errorf("err = %v, want the Kustomization to be forbidden", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	r := requests[0]
+	if r.Method != http.MethodPatch || r.URL.Path != "/apis/source.toolkit.fluxcd.io/v1/namespaces/gitops/gitrepositories/demo-export" {
+		t.Errorf("request = %s %s", r.Method, r.URL.Path)
+	}
+	if r.URL.Query().Get("fieldManager") != FieldManager || r.URL.Query().Get("force") != "true" {
+		t.Errorf("query = %s", r.URL.RawQuery)
+	}
+	for header, want := range map[string]string{
+		"Content-Type":     "application/apply-patch+yaml",
+		"Authorization":    "Bearer token",
+		"Impersonate-User": "alice",
+	} {
+		if got := r.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	if err := Apply(context.TODO(), cluster, []map[string]interface{}{{"kind": "Deployment"}}); err == nil {
+		t.Error("applied an unknown kind")
+	}
+}
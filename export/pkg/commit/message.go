@@ -0,0 +1,146 @@
+// Package commit builds the commits made by the export job.
+package commit
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+	"github.com/cooktheryan/gitops-primer/export/pkg/mirror"
+)
+
+// DefaultMessage is the template used when the Export does not set one
+const DefaultMessage = "Export {{.Name}} of namespace {{.Namespace}}"
+
+// Vars are the variables available to the commit message template
+type Vars struct {
+	// Namespace that was exported
+	Namespace string
+	// Name of the Export
+	Name string
+	// User the export ran as
+	User string
+	// Timestamp of the commit in RFC 3339 format
+	Timestamp string
+}
+
+// NewVars returns the Vars for an export run at now
+func NewVars(namespace, name, user string, now time.Time) Vars {
+	return Vars{
+		Namespace: namespace,
+		Name:      name,
+		User:      user,
+		Timestamp: now.UTC().Format(time.RFC3339),
+	}
+}
+
+// Change is a file changed by the commit
+type Change struct {
+	// Status is Added, Modified or Deleted
+	Status string
+	Path   string
+	// Object the file holds, if the file is a manifest
+	Object *manifest.Object
+}
+
+func (c Change) String() string {
+	if c.Object != nil {
+		return c.Object.String()
+	}
+	return c.Path
+}
+
+var statuses = map[string]string{
+	"A": "Added",
+	"M": "Modified",
+	"D": "Deleted",
+}
+
+// StagedChanges returns the changes staged in the repository in dir
+func StagedChanges(dir string) ([]Change, error) {
+	out, err := git(dir, "diff", "--cached", "--name-status", "--no-renames", "-z")
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	changes := []Change{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		change := Change{Status: statuses[fields[i]], Path: fields[i+1]}
+		if path.Base(change.Path) == mirror.IndexFile || strings.Contains("/"+change.Path, "/"+mirror.BaseDir+"/") {
+			// Changes to the index and the merge base follow from the
+			// other changes
+			continue
+		}
+		if change.Status == "" {
+			change.Status = "Modified"
+		}
+		// Deleted files are only found in the last commit
+		rev := ":"
+		if change.Status == "Deleted" {
+			rev = "HEAD:"
+		}
+		if data, err := git(dir, "show", rev+change.Path); err == nil {
+			if o, ok := manifest.Identify([]byte(data)); ok {
+				change.Object = &o
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// StagedDiff returns the unified diff of the changes staged in the
+// repository in dir, leaving out the index and the merge base
+func StagedDiff(dir string) (string, error) {
+	return git(dir, "diff", "--cached", "--no-renames", "--", ".",
+		":(exclude,glob)**/"+mirror.IndexFile,
+		":(exclude,glob)**/"+mirror.BaseDir+"/**")
+}
+
+// Message renders the subject from tmpl and lists the changes by status in
+// the body of the message
+func Message(tmpl string, vars Vars, changes []Change) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultMessage
+	}
+	t, err := template.New("message").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var message bytes.Buffer
+	if err := t.Execute(&message, vars); err != nil {
+		return "", err
+	}
+	for _, status := range []string{"Added", "Modified", "Deleted"} {
+		lines := []string{}
+		for _, change := range changes {
+			if change.Status == status {
+				lines = append(lines, "  "+change.String())
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(&message, "\n\n%s:\n%s", status, strings.Join(lines, "\n"))
+	}
+	return message.String() + "\n", nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,117 @@
+package commit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+)
+
+var vars = NewVars("demo", "nightly", "alice", time.Date(2021, 7, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60)))
+
+func ExampleMessage() {
+	message, _ := Message("", vars, nil)
+	fmt.Print(message)
+	// Output:
+	// Export nightly of namespace demo
+}
+
+func ExampleMessage_variables() {
+	message, _ := Message("{{.Namespace}}/{{.Name}} by {{.User}} at {{.Timestamp}}", vars, nil)
+	fmt.Print(message)
+	// Output:
+	// demo/nightly by alice at 2021-07-01T10:30:00Z
+}
+
+func ExampleMessage_changes() {
+	message, _ := Message("", vars, []Change{
+		{Status: "Deleted", Path: "demo/Route_route.openshift.io_v1_demo_web.yaml"},
+		{Status: "Modified", Path: "demo/Deployment_apps_v1_demo_web.yaml", Object: &manifest.Object{Kind: "Deployment", Name: "web"}},
+		{Status: "Added", Path: "demo/README.md"},
+		{Status: "Added", Path: "demo/ConfigMap_v1_demo_settings.yaml", Object: &manifest.Object{Kind: "ConfigMap", Name: "settings"}},
+	})
+	fmt.Print(message)
+	// Output:
+	// Export nightly of namespace demo
+	//
+	// Added:
+	//   ConfigMap/settings
+	//   demo/README.md
+	//
+	// Modified:
+	//   Deployment/web
+	//
+	// Deleted:
+	//   demo/Route_route.openshift.io_v1_demo_web.yaml
+}
+
+func TestMessageInvalidTemplate(t *testing.T) {
+	for _, tmpl := range []string{"{{.Cluster}}", "{{.Name"} {
+		if message, err := Message(tmpl, vars, nil); err == nil {
+			t.Errorf("Message(%q) = %q, want an error", tmpl, message)
+		}
+	}
+}
+
+func TestStagedChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	write := func(file, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) {
+		t.Helper()
+		if _, err := git(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q")
+	run("config", "user.email", "export@example.com")
+	run("config", "user.name", "export")
+	write("demo/Deployment_apps_v1_demo_web.yaml", "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 1\n")
+	write("demo/Route_route.openshift.io_v1_demo_web.yaml", "apiVersion: route.openshift.io/v1\nkind: Route\nmetadata:\n  name: web\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+
+	write("demo/Deployment_apps_v1_demo_web.yaml", "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n")
+	write("demo/ConfigMap_v1_demo_settings.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n")
+	write("demo/README.md", "# demo\n")
+	write("demo/.primer-index", "ConfigMap_v1_demo_settings.yaml\n")
+	write(".primer-base/demo/ConfigMap_v1_demo_settings.yaml", "kind: ConfigMap\n")
+	run("rm", "-q", "demo/Route_route.openshift.io_v1_demo_web.yaml")
+	run("add", "-A")
+
+	changes, err := StagedChanges(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, change := range changes {
+		got[change.String()] = change.Status
+	}
+	// The index and merge bases of the export are not listed
+	want := map[string]string{
+		"ConfigMap/settings": "Added",
+		"Deployment/web":     "Modified",
+		"Route/web":          "Deleted",
+		"demo/README.md":     "Added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
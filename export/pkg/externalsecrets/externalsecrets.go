@@ -0,0 +1,234 @@
+// Package externalsecrets replaces exported Secrets with ExternalSecrets of
+// the External Secrets Operator. Only the keys of a Secret are exported, its
+// values are read back from a SecretStore when the ExternalSecret is
+// applied.
+package externalsecrets
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+)
+
+// DefaultKeyTemplate reads every key of a Secret as a property of a single
+// key in the store
+const DefaultKeyTemplate = "{{.Namespace}}/{{.Name}}"
+
+// clusterManagedTypes are the types of Secret whose values are filled in by
+// the cluster the Secret is applied to, there is nothing to read back from
+// a store
+var clusterManagedTypes = map[string]bool{
+	"kubernetes.io/service-account-token": true,
+}
+
+// KeyVars are available to the key template
+type KeyVars struct {
+	// Namespace and name of the Secret
+	Namespace string
+	Name      string
+	// Key within the Secret
+	Key string
+}
+
+// Options configure Replace
+type Options struct {
+	// SecretStore the values are read from and its kind, SecretStore or
+	// ClusterSecretStore
+	Store     string
+	StoreKind string
+	// Go template of the key in the store holding a value. When the
+	// template does not use .Key the key of the Secret is read as a
+	// property of the rendered key
+	KeyTemplate string
+	// How often the values are read from the store
+	RefreshInterval string
+	// Namespace the Secrets were exported from
+	Namespace string
+}
+
+// ParseKeyTemplate parses a key template, an empty template is the
+// DefaultKeyTemplate
+func ParseKeyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultKeyTemplate
+	}
+	return template.New("key").Option("missingkey=error").Parse(text)
+}
+
+// Replace replaces every Secret manifest in dir with an ExternalSecret and
+// returns the files written. Secrets of the clusterManagedTypes are kept
+// without their values instead
+func Replace(dir string, opts Options) ([]string, error) {
+	keyTemplate, err := ParseKeyTemplate(opts.KeyTemplate)
+	if err != nil {
+		return nil, err
+	}
+	replaced := []string{}
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".yaml" {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if o, err := manifest.Parse(data); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		} else if o.Kind != "Secret" {
+			return nil
+		}
+		secret := &secret{}
+		if err := yaml.Unmarshal(data, secret); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		if clusterManagedTypes[secret.Type] {
+			return removeValues(file, data, info.Mode())
+		}
+		externalSecret, err := externalSecretFor(secret, keyTemplate, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		out, err := yaml.Marshal(externalSecret)
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(filepath.Dir(file), FileName(opts.Namespace, secret.Metadata.Name))
+		if err := ioutil.WriteFile(name, out, info.Mode()); err != nil {
+			return err
+		}
+		replaced = append(replaced, name)
+		return os.Remove(file)
+	})
+	return replaced, err
+}
+
+// removeValues keeps a Secret whose values are filled in by the cluster,
+// without the values
+func removeValues(file string, data []byte, mode os.FileMode) error {
+	o := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &o); err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(file), err)
+	}
+	delete(o, "data")
+	delete(o, "stringData")
+	out, err := yaml.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, out, mode)
+}
+
+// FileName returns the name of the file an ExternalSecret is written to,
+// named the same way as the other exported manifests
+func FileName(namespace, name string) string {
+	return strings.Join([]string{"ExternalSecret", "external-secrets.io", "v1", namespace, name}, "_") + ".yaml"
+}
+
+type metadata struct {
+	Name        string            `json:"name,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// secret holds the parts of a Secret kept by its ExternalSecret, the
+// values are only decoded to find the keys
+type secret struct {
+	Metadata   metadata               `json:"metadata"`
+	Type       string                 `json:"type,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	StringData map[string]interface{} `json:"stringData,omitempty"`
+}
+
+func externalSecretFor(s *secret, keyTemplate *template.Template, opts Options) (map[string]interface{}, error) {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, values := range []map[string]interface{}{s.Data, s.StringData} {
+		for key := range values {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	remoteKey := func(key string) (string, error) {
+		var out bytes.Buffer
+		err := keyTemplate.Execute(&out, KeyVars{Namespace: opts.Namespace, Name: s.Metadata.Name, Key: key})
+		return out.String(), err
+	}
+	// The template gives each key of the Secret a key of its own in the
+	// store when it renders differently for different keys
+	first, err := remoteKey("a")
+	if err != nil {
+		return nil, err
+	}
+	second, err := remoteKey("b")
+	if err != nil {
+		return nil, err
+	}
+	perKey := first != second
+
+	data := []interface{}{}
+	for _, key := range keys {
+		rendered, err := remoteKey(key)
+		if err != nil {
+			return nil, err
+		}
+		remoteRef := map[string]interface{}{"key": rendered}
+		if !perKey {
+			remoteRef["property"] = key
+		}
+		data = append(data, map[string]interface{}{
+			"secretKey": key,
+			"remoteRef": remoteRef,
+		})
+	}
+
+	storeKind := opts.StoreKind
+	if storeKind == "" {
+		storeKind = "SecretStore"
+	}
+	refreshInterval := opts.RefreshInterval
+	if refreshInterval == "" {
+		refreshInterval = "1h"
+	}
+	targetTemplate := map[string]interface{}{}
+	if s.Type != "" && s.Type != "Opaque" {
+		targetTemplate["type"] = s.Type
+	}
+	if len(s.Metadata.Labels) != 0 || len(s.Metadata.Annotations) != 0 {
+		targetTemplate["metadata"] = metadata{Labels: s.Metadata.Labels, Annotations: s.Metadata.Annotations}
+	}
+	target := map[string]interface{}{
+		"name":           s.Metadata.Name,
+		"creationPolicy": "Owner",
+	}
+	if len(targetTemplate) != 0 {
+		target["template"] = targetTemplate
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "external-secrets.io/v1",
+		"kind":       "ExternalSecret",
+		"metadata":   s.Metadata,
+		"spec": map[string]interface{}{
+			"refreshInterval": refreshInterval,
+			"secretStoreRef": map[string]interface{}{
+				"name": opts.Store,
+				"kind": storeKind,
+			},
+			"target": target,
+			"data":   data,
+		},
+	}, nil
+}
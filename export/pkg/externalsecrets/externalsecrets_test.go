@@ -0,0 +1,176 @@
+package externalsecrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	opaqueManifest    = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n  labels:\n    app: web\ntype: Opaque\ndata:\n  username: YWRtaW4=\nstringData:\n  password: hunter2\n"
+	tlsManifest       = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: web-tls\ntype: kubernetes.io/tls\ndata:\n  tls.crt: Y2VydA==\n  tls.key: a2V5\n"
+	tokenManifest     = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: robot-token\n  annotations:\n    kubernetes.io/service-account.name: robot\ntype: kubernetes.io/service-account-token\ndata:\n  token: c2VjcmV0\n"
+	configMapManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: production\n"
+)
+
+// export writes manifests into a new directory as crane does
+func export(t *testing.T, manifests map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// spec returns the spec of the ExternalSecret written for the Secret name
+func spec(t *testing.T, dir, name string) map[string]interface{} {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(dir, FileName("demo", name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &o); err != nil {
+		t.Fatal(err)
+	}
+	return o["spec"].(map[string]interface{})
+}
+
+func TestReplace(t *testing.T) {
+	dir := export(t, map[string]string{
+		"Secret_v1_demo_creds.yaml":       opaqueManifest,
+		"ConfigMap_v1_demo_settings.yaml": configMapManifest,
+	})
+	replaced, err := Replace(dir, Options{Store: "vault", Namespace: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, FileName("demo", "creds"))}; !reflect.DeepEqual(replaced, want) {
+		t.Errorf("replaced = %v, want %v", replaced, want)
+	}
+
+	// Every key is read as a property of the Secret in the store, the
+	// values are not exported
+	want := `apiVersion: external-secrets.io/v1
+kind: ExternalSecret
+metadata:
+  labels:
+    app: web
+  name: creds
+spec:
+  data:
+  - remoteRef:
+      key: demo/creds
+      property: password
+    secretKey: password
+  - remoteRef:
+      key: demo/creds
+      property: username
+    secretKey: username
+  refreshInterval: 1h
+  secretStoreRef:
+    kind: SecretStore
+    name: vault
+  target:
+    creationPolicy: Owner
+    name: creds
+    template:
+      metadata:
+        labels:
+          app: web
+`
+	got, err := ioutil.ReadFile(replaced[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("ExternalSecret =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Secret_v1_demo_creds.yaml")); !os.IsNotExist(err) {
+		t.Error("Secret was not removed")
+	}
+	if content, _ := ioutil.ReadFile(filepath.Join(dir, "ConfigMap_v1_demo_settings.yaml")); string(content) != configMapManifest {
+		t.Errorf("ConfigMap was changed to\n%s", content)
+	}
+}
+
+func TestReplaceKeyPerValue(t *testing.T) {
+	dir := export(t, map[string]string{"Secret_v1_demo_creds.yaml": opaqueManifest})
+	_, err := Replace(dir, Options{
+		Store:           "vault",
+		StoreKind:       "ClusterSecretStore",
+		KeyTemplate:     "apps/{{.Namespace}}/{{.Name}}/{{.Key}}",
+		RefreshInterval: "15m",
+		Namespace:       "demo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := spec(t, dir, "creds")
+	// A template using .Key reads each key from a key of its own
+	wantData := []interface{}{
+		map[string]interface{}{"secretKey": "password", "remoteRef": map[string]interface{}{"key": "apps/demo/creds/password"}},
+		map[string]interface{}{"secretKey": "username", "remoteRef": map[string]interface{}{"key": "apps/demo/creds/username"}},
+	}
+	if !reflect.DeepEqual(s["data"], wantData) {
+		t.Errorf("data = %v, want %v", s["data"], wantData)
+	}
+	wantStore := map[string]interface{}{"name": "vault", "kind": "ClusterSecretStore"}
+	if !reflect.DeepEqual(s["secretStoreRef"], wantStore) {
+		t.Errorf("secretStoreRef = %v, want %v", s["secretStoreRef"], wantStore)
+	}
+	if s["refreshInterval"] != "15m" {
+		t.Errorf("refreshInterval = %v", s["refreshInterval"])
+	}
+}
+
+func TestReplaceTypes(t *testing.T) {
+	dir := export(t, map[string]string{
+		"Secret_v1_demo_web-tls.yaml":     tlsManifest,
+		"Secret_v1_demo_robot-token.yaml": tokenManifest,
+	})
+	replaced, err := Replace(dir, Options{Store: "vault", Namespace: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, FileName("demo", "web-tls"))}; !reflect.DeepEqual(replaced, want) {
+		t.Errorf("replaced = %v, want %v", replaced, want)
+	}
+
+	// The Secret created from the store keeps its type
+	target := spec(t, dir, "web-tls")["target"].(map[string]interface{})
+	if template, _ := target["template"].(map[string]interface{}); template["type"] != "kubernetes.io/tls" {
+		t.Errorf("target = %v, want the kubernetes.io/tls type", target)
+	}
+
+	// Service account tokens are filled in by the cluster, so they are
+	// kept as Secrets without the token
+	token, err := ioutil.ReadFile(filepath.Join(dir, "Secret_v1_demo_robot-token.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "apiVersion: v1\nkind: Secret\nmetadata:\n  annotations:\n    kubernetes.io/service-account.name: robot\n  name: robot-token\ntype: kubernetes.io/service-account-token\n"
+	if string(token) != want {
+		t.Errorf("token Secret =\n%s\nwant\n%s", token, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName("demo", "robot-token"))); !os.IsNotExist(err) {
+		t.Error("ExternalSecret written for a service account token")
+	}
+}
+
+func TestReplaceInvalidTemplate(t *testing.T) {
+	for _, keyTemplate := range []string{"{{.Namespace", "{{.Cluster}}"} {
+		dir := export(t, map[string]string{"Secret_v1_demo_creds.yaml": opaqueManifest})
+		if _, err := Replace(dir, Options{Store: "vault", KeyTemplate: keyTemplate, Namespace: "demo"}); err == nil {
+			t.Errorf("%q: want an error", keyTemplate)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package helm turns the manifests of an export into a Helm chart. Every
+// manifest becomes a template and the values that usually differ between
+// environments, image tags, replica counts and Route hosts, are lifted out
+// into values.yaml.
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TemplatesDir is the directory of the chart holding the templates
+const TemplatesDir = "templates"
+
+// Options describe the chart written by Generate
+type Options struct {
+	// Name of the chart
+	Name string
+	// Description of the chart
+	Description string
+}
+
+type chart struct {
+	APIVersion  string `json:"apiVersion"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	Version     string `json:"version"`
+}
+
+// Generate turns the manifests in dir into a chart. The manifests are
+// moved to the templates directory and Chart.yaml and values.yaml are
+// written next to it
+func Generate(dir string, opts Options) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	if err := os.MkdirAll(filepath.Join(dir, TemplatesDir), 0755); err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	for _, file := range files {
+		name := filepath.Base(file)
+		if name == "Chart.yaml" || name == "values.yaml" {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		template, err := templateManifest(data, values)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, TemplatesDir, name), template, 0644); err != nil {
+			return err
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+
+	chartYAML, err := yaml.Marshal(chart{
+		APIVersion:  "v2",
+		Name:        opts.Name,
+		Description: opts.Description,
+		Type:        "application",
+		Version:     "0.1.0",
+	})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Chart.yaml"), chartYAML, 0644); err != nil {
+		return err
+	}
+	valuesYAML, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "values.yaml"), valuesYAML, 0644)
+}
+
+// lifter replaces fields of a manifest with references to values
+type lifter struct {
+	// section of values.yaml for the object, keyed by kind and name
+	section []string
+	values  map[string]interface{}
+	// expressions maps placeholders left in the manifest to the template
+	// expression that replaces them. Placeholders are closed off so that
+	// none is a prefix of another, such as PRIMER-VALUE-1 of PRIMER-VALUE-10
+	expressions map[string]string
+}
+
+// lift stores value under path in the section of the object and returns
+// the placeholder to put in the manifest instead
+func (l *lifter) lift(value interface{}, path ...string) string {
+	full := append(append([]string{}, l.section...), path...)
+	setValue(l.values, full, value)
+	placeholder := fmt.Sprintf("PRIMER-VALUE-%d-END", len(l.expressions))
+	quoted := make([]string, len(full))
+	for i, key := range full {
+		quoted[i] = fmt.Sprintf("%q", key)
+	}
+	l.expressions[placeholder] = "{{ index .Values " + strings.Join(quoted, " ") + " }}"
+	return placeholder
+}
+
+// templateManifest lifts values out of a manifest and returns the template
+func templateManifest(data []byte, values map[string]interface{}) ([]byte, error) {
+	o := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+	kind, _ := o["kind"].(string)
+	name, _ := lookup(o, "metadata", "name").(string)
+	if kind == "" || name == "" {
+		return escape(data), nil
+	}
+	l := &lifter{
+		section:     []string{strings.ToLower(kind[:1]) + kind[1:], name},
+		values:      values,
+		expressions: map[string]string{},
+	}
+
+	spec, _ := o["spec"].(map[string]interface{})
+	switch kind {
+	case "Deployment", "StatefulSet", "ReplicaSet", "DeploymentConfig":
+		if replicas, ok := spec["replicas"]; ok {
+			spec["replicas"] = l.lift(replicas, "replicas")
+		}
+		liftImages(l, lookup(spec, "template", "spec"))
+	case "DaemonSet", "Job":
+		liftImages(l, lookup(spec, "template", "spec"))
+	case "CronJob":
+		liftImages(l, lookup(spec, "jobTemplate", "spec", "template", "spec"))
+	case "Route":
+		if host, ok := spec["host"]; ok {
+			spec["host"] = l.lift(host, "host")
+		}
+	}
+	if len(l.expressions) == 0 {
+		return escape(data), nil
+	}
+
+	out, err := yaml.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	template := string(escape(out))
+	for placeholder, expression := range l.expressions {
+		template = strings.Replace(template, placeholder, expression, 1)
+	}
+	return []byte(template), nil
+}
+
+// liftImages lifts the repository and tag of the image of every container
+// in a pod spec
+func liftImages(l *lifter, podSpec interface{}) {
+	spec, ok := podSpec.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := spec[field].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := container["name"].(string)
+			image, _ := container["image"].(string)
+			if name == "" || image == "" {
+				continue
+			}
+			repository, tag := splitImage(image)
+			if tag == "" {
+				container["image"] = l.lift(repository, field, name, "image", "repository")
+				continue
+			}
+			container["image"] = l.lift(repository, field, name, "image", "repository") + ":" + l.lift(tag, field, name, "image", "tag")
+		}
+	}
+}
+
+// splitImage splits an image reference into its repository and tag. An
+// image referenced by digest is left whole
+func splitImage(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+// escape keeps Helm from rendering braces that are part of the exported
+// objects, for example within a ConfigMap
+func escape(data []byte) []byte {
+	s := strings.Replace(string(data), "{{", "{{ \"{{\" }}", -1)
+	return []byte(s)
+}
+
+func lookup(o interface{}, path ...string) interface{} {
+	for _, key := range path {
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		o = m[key]
+	}
+	return o
+}
+
+func setValue(values map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := values[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[path[len(path)-1]] = value
+}
@@ -0,0 +1,146 @@
+package helm
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+var update = flag.Bool("update", false, "update the chart in testdata/chart")
+
+// TestGenerate turns testdata/manifests into a chart and compares it with
+// testdata/chart
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	manifests, err := filepath.Glob(filepath.Join("testdata", "manifests", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, manifest := range manifests {
+		data, err := ioutil.ReadFile(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.Base(manifest)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Generate(dir, Options{Name: "demo", Description: "Export of namespace demo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "chart")
+	if *update {
+		if err := os.RemoveAll(golden); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyDir(dir, golden); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, want := readDir(t, dir), readDir(t, golden)
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s was not generated", name)
+		}
+	}
+	for name, content := range got {
+		if content != want[name] {
+			t.Errorf("%s =\n%s\nwant\n%s", name, content, want[name])
+		}
+	}
+}
+
+// TestGenerateRenders renders the templates of the chart with its values
+// and compares the result with the exported manifests
+func TestGenerateRenders(t *testing.T) {
+	chart := readDir(t, filepath.Join("testdata", "chart"))
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(chart["values.yaml"]), &values); err != nil {
+		t.Fatal(err)
+	}
+	for name, tmpl := range chart {
+		if !strings.HasPrefix(name, TemplatesDir+"/") {
+			continue
+		}
+		if strings.Contains(tmpl, "PRIMER-VALUE") {
+			t.Errorf("%s has a placeholder left:\n%s", name, tmpl)
+		}
+		parsed, err := template.New(name).Option("missingkey=error").Parse(tmpl)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var rendered bytes.Buffer
+		if err := parsed.Execute(&rendered, map[string]interface{}{"Values": values}); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		manifest, err := ioutil.ReadFile(filepath.Join("testdata", "manifests", filepath.Base(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, want interface{}
+		if err := yaml.Unmarshal(rendered.Bytes(), &got); err != nil {
+			t.Errorf("%s renders invalid YAML: %v\n%s", name, err, rendered.Bytes())
+			continue
+		}
+		if err := yaml.Unmarshal(manifest, &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s renders\n%s\nwant\n%s", name, rendered.Bytes(), manifest)
+		}
+	}
+}
+
+// readDir returns the content of the files below dir by their slash
+// separated path
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func copyDir(from, to string) error {
+	return filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(to, rel), 0755)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(to, rel), data, 0644)
+	})
+}
@@ -0,0 +1,354 @@
+// Package kustomize turns the manifests of an export into a kustomization
+// that can be applied with kubectl apply -k or used by a GitOps tool. The
+// kustomization written is marked as generated, changes made to a marked
+// kustomization in the repository are kept by the next export and one
+// without the marker is left alone.
+package kustomize
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/mirror"
+)
+
+// File is the name of the kustomization written by Generate
+const File = "kustomization.yaml"
+
+// Marker is the first line of a kustomization written by Generate
+const Marker = "# Generated by GitOps Primer, remove this line to maintain the kustomization by hand\n"
+
+// Options change the kustomization written by Generate
+type Options struct {
+	// Generators replaces ConfigMaps and Secrets with configMapGenerator
+	// and secretGenerator entries backed by a file per key
+	Generators bool
+	// Target is the directory of the repository the export is synced to.
+	// Files matching its IgnoreFile are left out of the kustomization and
+	// the kustomization already there is merged with the new one
+	Target string
+}
+
+type kustomization struct {
+	APIVersion         string           `json:"apiVersion"`
+	Kind               string           `json:"kind"`
+	Resources          []string         `json:"resources,omitempty"`
+	ConfigMapGenerator []generator      `json:"configMapGenerator,omitempty"`
+	SecretGenerator    []generator      `json:"secretGenerator,omitempty"`
+	GeneratorOptions   *generatorOption `json:"generatorOptions,omitempty"`
+}
+
+type generator struct {
+	Name    string           `json:"name"`
+	Type    string           `json:"type,omitempty"`
+	Files   []string         `json:"files,omitempty"`
+	Options *generatorOption `json:"options,omitempty"`
+}
+
+type generatorOption struct {
+	Labels                map[string]string `json:"labels,omitempty"`
+	Annotations           map[string]string `json:"annotations,omitempty"`
+	DisableNameSuffixHash bool              `json:"disableNameSuffixHash,omitempty"`
+}
+
+// object holds the fields of a manifest needed to build generators
+type object struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name        string            `json:"name"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Type       string            `json:"type"`
+	Data       map[string]string `json:"data"`
+	BinaryData map[string]string `json:"binaryData"`
+	StringData map[string]string `json:"stringData"`
+}
+
+// Generate writes a kustomization listing every manifest in dir
+func Generate(dir string, opts Options) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	ignore := &mirror.Ignore{}
+	if opts.Target != "" {
+		if ignore, err = mirror.LoadIgnore(opts.Target); err != nil {
+			return err
+		}
+	}
+	// Anything left in dir is written to the target so drop the previous
+	// kustomization up front, it is only written back below
+	if err := os.Remove(filepath.Join(dir, File)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	previous, owned, err := readPrevious(opts.Target)
+	if err != nil {
+		return err
+	}
+	if previous != nil && !bytes.HasPrefix(previous, []byte(Marker)) {
+		// Maintained by hand, keep it when the export wrote it before so
+		// that it is not pruned
+		if owned[File] {
+			return ioutil.WriteFile(filepath.Join(dir, File), previous, 0644)
+		}
+		return nil
+	}
+
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		// The kustomization can only refer to the files that are written
+		if name == File || ignore.Match(name) {
+			continue
+		}
+		if opts.Generators {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			o := object{}
+			if err := yaml.Unmarshal(data, &o); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			switch o.Kind {
+			case "ConfigMap":
+				g, err := writeGenerator(dir, "configmaps", o, false)
+				if err != nil {
+					return err
+				}
+				if !ignored(ignore, g) {
+					k.ConfigMapGenerator = append(k.ConfigMapGenerator, g)
+				}
+				if err := os.Remove(file); err != nil {
+					return err
+				}
+				continue
+			case "Secret":
+				g, err := writeGenerator(dir, "secrets", o, true)
+				if err != nil {
+					return err
+				}
+				if !ignored(ignore, g) {
+					k.SecretGenerator = append(k.SecretGenerator, g)
+				}
+				if err := os.Remove(file); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		k.Resources = append(k.Resources, name)
+	}
+	if len(k.ConfigMapGenerator) > 0 || len(k.SecretGenerator) > 0 {
+		// Keep the names of the exported objects
+		k.GeneratorOptions = &generatorOption{DisableNameSuffixHash: true}
+	}
+	if ignore.Match(File) {
+		return nil
+	}
+
+	generated, err := toMap(k)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		existing := map[string]interface{}{}
+		if err := yaml.Unmarshal(previous, &existing); err != nil {
+			return fmt.Errorf("%s: %v", File, err)
+		}
+		generated = merge(existing, generated, owned)
+	}
+	out, err := yaml.Marshal(generated)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, File), append([]byte(Marker), out...), 0644)
+}
+
+// readPrevious returns the kustomization in the target directory, nil
+// when there is none, along with the files the export wrote there
+func readPrevious(target string) ([]byte, map[string]bool, error) {
+	owned := map[string]bool{}
+	if target == "" {
+		return nil, owned, nil
+	}
+	index, err := mirror.ReadIndex(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range index {
+		owned[file] = true
+	}
+	previous, err := ioutil.ReadFile(filepath.Join(target, File))
+	if os.IsNotExist(err) {
+		return nil, owned, nil
+	}
+	return previous, owned, err
+}
+
+// ignored reports whether any file of g matches the IgnoreFile, leaving
+// the generator without its files
+func ignored(ignore *mirror.Ignore, g generator) bool {
+	for _, file := range g.Files {
+		if ignore.Match(generatorFile(file)) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatorFile returns the path of a generator files entry, which may be
+// prefixed with the key
+func generatorFile(entry string) string {
+	if i := strings.Index(entry, "="); i >= 0 {
+		return entry[i+1:]
+	}
+	return entry
+}
+
+// toMap converts k into the generic form used to merge kustomizations
+func toMap(k kustomization) (map[string]interface{}, error) {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	return m, json.Unmarshal(data, &m)
+}
+
+// merge updates the existing kustomization with the generated one. Fields
+// the export does not generate are kept as they are, as are the resources
+// and generators added by hand, those not referring to files the export
+// wrote before
+func merge(existing, generated map[string]interface{}, owned map[string]bool) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range existing {
+		merged[key] = value
+	}
+	merged["apiVersion"] = generated["apiVersion"]
+	merged["kind"] = generated["kind"]
+
+	resources, _ := generated["resources"].([]interface{})
+	listed := map[interface{}]bool{}
+	for _, resource := range resources {
+		listed[resource] = true
+	}
+	existingResources, _ := existing["resources"].([]interface{})
+	for _, resource := range existingResources {
+		name, ok := resource.(string)
+		if ok && (owned[name] || listed[name]) {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	setList(merged, "resources", resources)
+
+	for _, key := range []string{"configMapGenerator", "secretGenerator"} {
+		generators, _ := generated[key].([]interface{})
+		names := map[interface{}]bool{}
+		for _, g := range generators {
+			names[g.(map[string]interface{})["name"]] = true
+		}
+		existingGenerators, _ := existing[key].([]interface{})
+		for _, g := range existingGenerators {
+			if fields, ok := g.(map[string]interface{}); ok && (names[fields["name"]] || ownsFiles(fields, owned)) {
+				continue
+			}
+			generators = append(generators, g)
+		}
+		setList(merged, key, generators)
+	}
+
+	if options, ok := generated["generatorOptions"].(map[string]interface{}); ok {
+		existingOptions, _ := existing["generatorOptions"].(map[string]interface{})
+		for key, value := range existingOptions {
+			if _, ok := options[key]; !ok {
+				options[key] = value
+			}
+		}
+		merged["generatorOptions"] = options
+	}
+	return merged
+}
+
+// ownsFiles reports whether a generator refers to files the export wrote
+func ownsFiles(g map[string]interface{}, owned map[string]bool) bool {
+	files, _ := g["files"].([]interface{})
+	for _, file := range files {
+		if entry, ok := file.(string); ok && owned[generatorFile(entry)] {
+			return true
+		}
+	}
+	return false
+}
+
+// setList sets key of k to list, removing it when the list is empty
+func setList(k map[string]interface{}, key string, list []interface{}) {
+	if len(list) == 0 {
+		delete(k, key)
+		return
+	}
+	k[key] = list
+}
+
+// writeGenerator writes each key of o to a file of its own in
+// dir/kind/name and returns the generator for the files. The data of
+// Secrets and the binaryData of ConfigMaps is base64 encoded
+func writeGenerator(dir, kind string, o object, encoded bool) (generator, error) {
+	g := generator{Name: o.Metadata.Name, Type: o.Type}
+	if len(o.Metadata.Labels) > 0 || len(o.Metadata.Annotations) > 0 {
+		g.Options = &generatorOption{Labels: o.Metadata.Labels, Annotations: o.Metadata.Annotations}
+	}
+	files := map[string][]byte{}
+	for key, value := range o.StringData {
+		files[key] = []byte(value)
+	}
+	for key, value := range o.Data {
+		if !encoded {
+			files[key] = []byte(value)
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return g, fmt.Errorf("%s %s key %s: %v", o.Kind, o.Metadata.Name, key, err)
+		}
+		files[key] = decoded
+	}
+	for key, value := range o.BinaryData {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return g, fmt.Errorf("%s %s key %s: %v", o.Kind, o.Metadata.Name, key, err)
+		}
+		files[key] = decoded
+	}
+
+	keyDir := filepath.Join(dir, kind, o.Metadata.Name)
+	if err := os.MkdirAll(keyDir, 0755); err != nil {
+		return g, err
+	}
+	for key, value := range files {
+		if strings.Contains(key, "/") {
+			return g, fmt.Errorf("%s %s has an invalid key %q", o.Kind, o.Metadata.Name, key)
+		}
+		if err := ioutil.WriteFile(filepath.Join(keyDir, key), value, 0644); err != nil {
+			return g, err
+		}
+		g.Files = append(g.Files, key+"="+filepath.ToSlash(filepath.Join(kind, o.Metadata.Name, key)))
+	}
+	sort.Strings(g.Files)
+	return g, nil
+}
@@ -0,0 +1,279 @@
+package kustomize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/mirror"
+)
+
+const (
+	deploymentManifest = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+	configMapManifest  = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  labels:\n    app: web\ndata:\n  mode: production\nbinaryData:\n  logo.png: iVBORw==\n"
+	secretManifest     = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\n  annotations:\n    owner: team-a\ntype: kubernetes.io/basic-auth\ndata:\n  username: YWRtaW4=\nstringData:\n  password: hunter2\n"
+)
+
+// export writes manifests into a new directory as crane does
+func export(t *testing.T, manifests map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, dir, file string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestGenerateResources(t *testing.T) {
+	dir := export(t, map[string]string{
+		"Deployment_apps_v1_demo_web.yaml": deploymentManifest,
+		"ConfigMap_v1_demo_settings.yaml":  configMapManifest,
+		File:                               "stale",
+	})
+	if err := Generate(dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Marker + `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ConfigMap_v1_demo_settings.yaml
+- Deployment_apps_v1_demo_web.yaml
+`
+	if got := readFile(t, dir, File); got != want {
+		t.Errorf("%s =\n%s\nwant\n%s", File, got, want)
+	}
+}
+
+func TestGenerateGenerators(t *testing.T) {
+	dir := export(t, map[string]string{
+		"Deployment_apps_v1_demo_web.yaml": deploymentManifest,
+		"ConfigMap_v1_demo_settings.yaml":  configMapManifest,
+		"Secret_v1_demo_creds.yaml":        secretManifest,
+	})
+	if err := Generate(dir, Options{Generators: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Marker + `apiVersion: kustomize.config.k8s.io/v1beta1
+configMapGenerator:
+- files:
+  - logo.png=configmaps/settings/logo.png
+  - mode=configmaps/settings/mode
+  name: settings
+  options:
+    labels:
+      app: web
+generatorOptions:
+  disableNameSuffixHash: true
+kind: Kustomization
+resources:
+- Deployment_apps_v1_demo_web.yaml
+secretGenerator:
+- files:
+  - password=secrets/creds/password
+  - username=secrets/creds/username
+  name: creds
+  options:
+    annotations:
+      owner: team-a
+  type: kubernetes.io/basic-auth
+`
+	if got := readFile(t, dir, File); got != want {
+		t.Errorf("%s =\n%s\nwant\n%s", File, got, want)
+	}
+
+	// Each key is written to a file of its own, decoded
+	for file, want := range map[string]string{
+		"configmaps/settings/mode":     "production",
+		"configmaps/settings/logo.png": "\x89PNG",
+		"secrets/creds/username":       "admin",
+		"secrets/creds/password":       "hunter2",
+	} {
+		if got := readFile(t, dir, file); got != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+	// and the manifests the generators replace are removed
+	for _, name := range []string{"ConfigMap_v1_demo_settings.yaml", "Secret_v1_demo_creds.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", name)
+		}
+	}
+}
+
+func TestGenerateInvalidData(t *testing.T) {
+	for name, manifest := range map[string]string{
+		"ConfigMap_v1_demo_settings.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  a/b: c\n",
+		"Secret_v1_demo_creds.yaml":       "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ndata:\n  token: '!!'\n",
+	} {
+		dir := export(t, map[string]string{name: manifest})
+		if err := Generate(dir, Options{Generators: true}); err == nil {
+			t.Errorf("%s: want an error", name)
+		}
+	}
+}
+
+func TestGenerateIgnored(t *testing.T) {
+	dir := export(t, map[string]string{
+		"Deployment_apps_v1_demo_web.yaml": deploymentManifest,
+		"ConfigMap_v1_demo_settings.yaml":  configMapManifest,
+		"Secret_v1_demo_creds.yaml":        secretManifest,
+	})
+	target := export(t, map[string]string{mirror.IgnoreFile: "Secret_*\nconfigmaps/settings/\n"})
+	if err := Generate(dir, Options{Target: target}); err != nil {
+		t.Fatal(err)
+	}
+	want := Marker + `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ConfigMap_v1_demo_settings.yaml
+- Deployment_apps_v1_demo_web.yaml
+`
+	if got := readFile(t, dir, File); got != want {
+		t.Errorf("%s =\n%s\nwant\n%s", File, got, want)
+	}
+
+	// Generators whose files are ignored are left out as well
+	dir = export(t, map[string]string{
+		"Deployment_apps_v1_demo_web.yaml": deploymentManifest,
+		"ConfigMap_v1_demo_settings.yaml":  configMapManifest,
+	})
+	if err := Generate(dir, Options{Generators: true, Target: target}); err != nil {
+		t.Fatal(err)
+	}
+	want = Marker + `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- Deployment_apps_v1_demo_web.yaml
+`
+	if got := readFile(t, dir, File); got != want {
+		t.Errorf("%s with generators =\n%s\nwant\n%s", File, got, want)
+	}
+
+	// as is the kustomization itself
+	dir = export(t, map[string]string{"Deployment_apps_v1_demo_web.yaml": deploymentManifest})
+	target = export(t, map[string]string{mirror.IgnoreFile: File + "\n"})
+	if err := Generate(dir, Options{Target: target}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, File)); !os.IsNotExist(err) {
+		t.Errorf("ignored %s was written", File)
+	}
+}
+
+func TestGenerateMerge(t *testing.T) {
+	target := export(t, map[string]string{
+		File: Marker + `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namePrefix: prod-
+resources:
+- Deployment_apps_v1_demo_old.yaml
+- ../base
+- Deployment_apps_v1_demo_web.yaml
+configMapGenerator:
+- name: old
+  files:
+  - mode=configmaps/old/mode
+- name: extra
+  envs:
+  - extra.env
+generatorOptions:
+  labels:
+    team: a
+patches:
+- path: patches/replicas.yaml
+`,
+		mirror.IndexFile: "Deployment_apps_v1_demo_old.yaml\nDeployment_apps_v1_demo_web.yaml\nconfigmaps/old/mode\n" + File + "\n",
+	})
+	dir := export(t, map[string]string{
+		"Deployment_apps_v1_demo_web.yaml": deploymentManifest,
+		"ConfigMap_v1_demo_settings.yaml":  configMapManifest,
+	})
+	if err := Generate(dir, Options{Generators: true, Target: target}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The objects no longer exported are dropped, everything added by
+	// hand is kept
+	want := Marker + `apiVersion: kustomize.config.k8s.io/v1beta1
+configMapGenerator:
+- files:
+  - logo.png=configmaps/settings/logo.png
+  - mode=configmaps/settings/mode
+  name: settings
+  options:
+    labels:
+      app: web
+- envs:
+  - extra.env
+  name: extra
+generatorOptions:
+  disableNameSuffixHash: true
+  labels:
+    team: a
+kind: Kustomization
+namePrefix: prod-
+patches:
+- path: patches/replicas.yaml
+resources:
+- Deployment_apps_v1_demo_web.yaml
+- ../base
+`
+	got := readFile(t, dir, File)
+	if got != want {
+		t.Errorf("%s =\n%s\nwant\n%s", File, got, want)
+	}
+
+	// Exporting again leaves the merged kustomization as it is
+	if err := ioutil.WriteFile(filepath.Join(target, File), []byte(got), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir = export(t, map[string]string{
+		"Deployment_apps_v1_demo_web.yaml": deploymentManifest,
+		"ConfigMap_v1_demo_settings.yaml":  configMapManifest,
+	})
+	if err := Generate(dir, Options{Generators: true, Target: target}); err != nil {
+		t.Fatal(err)
+	}
+	if again := readFile(t, dir, File); again != got {
+		t.Errorf("%s changed on the next export to\n%s", File, again)
+	}
+}
+
+func TestGenerateUnmarked(t *testing.T) {
+	const own = "resources:\n- Deployment_apps_v1_demo_web.yaml\n"
+	for name, index := range map[string]string{
+		"added by hand": "",
+		// such as when the marker was removed
+		"written by the export": File + "\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			target := export(t, map[string]string{File: own, mirror.IndexFile: index})
+			dir := export(t, map[string]string{"Deployment_apps_v1_demo_web.yaml": deploymentManifest})
+			if err := Generate(dir, Options{Target: target}); err != nil {
+				t.Fatal(err)
+			}
+			content, err := ioutil.ReadFile(filepath.Join(dir, File))
+			if index == "" && !os.IsNotExist(err) {
+				t.Errorf("%s was written", File)
+			}
+			// Written back unchanged so that it is not pruned
+			if index != "" && string(content) != own {
+				t.Errorf("%s = %q, want %q", File, content, own)
+			}
+		})
+	}
+}
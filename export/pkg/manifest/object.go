@@ -0,0 +1,71 @@
+// Package manifest reads the manifests written to the repository by an
+// export.
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Object identifies the object a manifest was exported from
+type Object struct {
+	Kind string
+	Name string
+}
+
+func (o Object) String() string {
+	return o.Kind + "/" + o.Name
+}
+
+// header holds the fields of a manifest that identify its object
+type header struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+}
+
+// Identify returns the object described by a manifest, false when the
+// manifest cannot be parsed or does not name an object
+func Identify(data []byte) (Object, bool) {
+	o, err := Parse(data)
+	return o, err == nil
+}
+
+// Parse returns the object described by a manifest. The steps handling
+// Secrets use it so that a manifest they cannot make sense of fails the
+// export, rather than a Secret being written out as it is
+func Parse(data []byte) (Object, error) {
+	h := header{}
+	if err := yaml.Unmarshal(data, &h); err != nil {
+		return Object{}, err
+	}
+	if h.Kind == "" || h.Metadata.Name == "" {
+		return Object{}, fmt.Errorf("manifest does not have a kind and name")
+	}
+	return Object{Kind: h.Kind, Name: h.Metadata.Name}, nil
+}
+
+// Kind returns the kind of the object in a file written by an output
+// format, empty for files that are not objects such as a values.yaml. Helm
+// templates are not always valid YAML so the top level kind is looked for
+// in them instead. Any other file that cannot be parsed is an error
+func Kind(data []byte) (string, error) {
+	h := header{}
+	err := yaml.Unmarshal(data, &h)
+	if err == nil {
+		return h.Kind, nil
+	}
+	if !bytes.Contains(data, []byte("{{")) {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "kind:") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "kind:")), `"'`), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,55 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	objects := map[string]Object{
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: demo\n": {Kind: "ConfigMap", Name: "settings"},
+		// The name of the object, not one of its labels
+		"kind: \"Secret\"\nmetadata:\n  labels:\n    name: other\n  name: 'creds'\n": {Kind: "Secret", Name: "creds"},
+		// Values longer than a line of bufio.Scanner
+		"apiVersion: v1\ndata:\n  big: " + strings.Repeat("a", 100*1024) + "\nkind: Secret\nmetadata:\n  name: large\n": {Kind: "Secret", Name: "large"},
+	}
+	for manifest, want := range objects {
+		got, err := Parse([]byte(manifest))
+		if err != nil {
+			t.Errorf("Parse(%.40q): %v", manifest, err)
+		} else if got != want {
+			t.Errorf("Parse(%.40q) = %v, want %v", manifest, got, want)
+		}
+		if o, ok := Identify([]byte(manifest)); !ok || o != want {
+			t.Errorf("Identify(%.40q) = %v, %v", manifest, o, ok)
+		}
+	}
+
+	for _, manifest := range []string{
+		"apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\n",
+		"metadata:\n  name: settings\n",
+		"kind: Secret\nmetadata: [\n",
+	} {
+		if o, err := Parse([]byte(manifest)); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", manifest, o)
+		}
+	}
+}
+
+func TestKind(t *testing.T) {
+	kinds := map[string]string{
+		"kind: Secret\nmetadata:\n  name: creds\n": "Secret",
+		// values.yaml of a chart
+		"deployment:\n  web:\n    replicas: 2\n": "",
+		// Templates of a chart are not always valid YAML
+		"kind: Deployment\nspec:\n  replicas: {{ index .Values \"deployment\" \"web\" \"replicas\" }}\n": "Deployment",
+	}
+	for manifest, want := range kinds {
+		if got, err := Kind([]byte(manifest)); err != nil || got != want {
+			t.Errorf("Kind(%q) = %q, %v, want %q", manifest, got, err, want)
+		}
+	}
+	if _, err := Kind([]byte("kind: Secret\nmetadata: [\n")); err == nil {
+		t.Error("expected an error for a manifest that cannot be parsed")
+	}
+}
@@ -0,0 +1,124 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// loadIgnore writes patterns to the IgnoreFile of a new directory and
+// loads it again
+func loadIgnore(t *testing.T, patterns string) *Ignore {
+	t.Helper()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, IgnoreFile), []byte(patterns), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ignore, err := LoadIgnore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ignore
+}
+
+func TestLoadIgnoreWithoutFile(t *testing.T) {
+	ignore, err := LoadIgnore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"README.md", "dir/Deployment_apps_v1_demo_web.yaml"} {
+		if ignore.Match(file) {
+			t.Errorf("%s is ignored", file)
+		}
+	}
+}
+
+// TestIgnoreMatch checks each pattern against the paths it should and
+// should not match, following the gitignore rules
+func TestIgnoreMatch(t *testing.T) {
+	patterns := map[string]map[string]bool{
+		"# README.md\n\n   \n": {
+			"README.md": false,
+		},
+		"*.md\n": {
+			"README.md":     true,
+			"docs/guide.md": true,
+			"a/b/c.md":      true,
+			"README.mdx":    false,
+			"md":            false,
+		},
+		"/kustomization.yaml\n": {
+			"kustomization.yaml":          true,
+			"overlays/kustomization.yaml": false,
+		},
+		"patches/*.yaml\n": {
+			"patches/replicas.yaml":      true,
+			"base/patches/replicas.yaml": false,
+			"patches/deep/replicas.yaml": false,
+		},
+		"patches/\n": {
+			"patches/replicas.yaml":          true,
+			"overlays/patches/replicas.yaml": true,
+			"patches":                        false,
+			"patches.yaml":                   false,
+		},
+		"**/ci.yaml\n": {
+			"ci.yaml":                   true,
+			".github/workflows/ci.yaml": true,
+			"ci.yaml.bak":               false,
+		},
+		"docs/**/*.yaml\n": {
+			"docs/a.yaml":       true,
+			"docs/x/y/a.yaml":   true,
+			"other/docs/a.yaml": false,
+			"docs/a.md":         false,
+		},
+		"local/**\n": {
+			"local/a.yaml":   true,
+			"local/x/b.yaml": true,
+			"local":          false,
+		},
+		"?.txt\n[ab].yaml\n[!x]y.json\n": {
+			"a.txt":   true,
+			"b.yaml":  true,
+			"ay.json": true,
+			"ab.txt":  false,
+			"c.yaml":  false,
+			"xy.json": false,
+			"a/.txt":  false,
+		},
+		"*.yaml\n!Deployment_apps_v1_demo_web.yaml\n": {
+			"ConfigMap_v1_demo_settings.yaml":  true,
+			"Deployment_apps_v1_demo_web.yaml": false,
+		},
+		// The last matching rule wins
+		"!keep.yaml\n*.yaml\n": {
+			"keep.yaml": true,
+		},
+		// As with git a file in an ignored directory cannot be re-included
+		"local/\n!local/keep.yaml\n": {
+			"local/keep.yaml":  true,
+			"local/other.yaml": true,
+		},
+		"\\#notes\n\\!important\n": {
+			"#notes":     true,
+			"!important": true,
+			"notes":      false,
+			"important":  false,
+		},
+		"README.md  \t\n": {
+			"README.md": true,
+		},
+		"a.yaml\n": {
+			"abyaml": false,
+		},
+	}
+	for pattern, files := range patterns {
+		ignore := loadIgnore(t, pattern)
+		for file, want := range files {
+			if got := ignore.Match(file); got != want {
+				t.Errorf("%q matching %s = %v, want %v", pattern, file, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package mirror
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// BaseDir holds the last exported version of every file when merging. It
+// is the common ancestor of changes made to a file in the repository and
+// changes made to the object in the cluster. It is kept at the root of the
+// repository, outside the directories synced to the cluster, with the
+// bases of each target directory below their path
+const BaseDir = ".primer-base"
+
+// merge writes the exported version of file into to, keeping any changes
+// made to the file in the repository since the last export. It reports a
+// conflict, leaving the file as it is, when both changed the same lines
+func merge(from, to, base, file string) (bool, error) {
+	exported, err := ioutil.ReadFile(filepath.Join(from, file))
+	if err != nil {
+		return false, err
+	}
+	current, err := readOptional(filepath.Join(to, file))
+	if err != nil {
+		return false, err
+	}
+	last, err := readOptional(filepath.Join(base, file))
+	if err != nil {
+		return false, err
+	}
+
+	content := exported
+	switch {
+	case current == nil || bytes.Equal(current, exported):
+		// A new object, or the repository already matches the cluster
+	case last == nil:
+		// The file was written before merging was enabled. Keep the
+		// repository version and take this export as the base, so the
+		// changes made in the cluster from now on are merged into it
+		content = current
+	case bytes.Equal(current, last):
+		// Only the cluster changed
+	case bytes.Equal(exported, last):
+		// Only the repository changed
+		content = current
+	default:
+		merged, conflict, err := mergeFile(current, last, exported)
+		if err != nil || conflict {
+			return conflict, err
+		}
+		content = merged
+	}
+	if err := writeFile(filepath.Join(to, file), content); err != nil {
+		return false, err
+	}
+	return false, writeFile(filepath.Join(base, file), exported)
+}
+
+// prune deletes file from to unless it was changed in the repository
+// since the last export, which is reported as a conflict
+func prune(to, base, file string, merging bool) (bool, error) {
+	if merging {
+		current, err := readOptional(filepath.Join(to, file))
+		if err != nil {
+			return false, err
+		}
+		last, err := readOptional(filepath.Join(base, file))
+		if err != nil {
+			return false, err
+		}
+		if current != nil && last != nil && !bytes.Equal(current, last) {
+			return true, nil
+		}
+		if err := remove(base, file); err != nil {
+			return false, err
+		}
+	}
+	return false, remove(to, file)
+}
+
+// mergeFile runs a three way merge with git merge-file
+func mergeFile(current, base, exported []byte) ([]byte, bool, error) {
+	dir, err := ioutil.TempDir("", "primer-merge")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string][]byte{"current": current, "base": base, "exported": exported} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			return nil, false, err
+		}
+	}
+	cmd := exec.Command("git", "merge-file", "-p", "-q", filepath.Join(dir, "current"), filepath.Join(dir, "base"), filepath.Join(dir, "exported"))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	merged, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		// The exit code is the number of conflicts
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("git merge-file: %v: %s", err, stderr.String())
+	}
+	return merged, false, nil
+}
+
+func readOptional(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
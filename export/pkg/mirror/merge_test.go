@@ -0,0 +1,210 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const settings = "ConfigMap_v1_demo_settings.yaml"
+
+const settingsManifest = "kind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  a: one\n  b: two\n  c: three\n"
+
+// repository runs successive merging exports into the target directory of
+// a repository, as the export job does on each run
+type repository struct {
+	t    *testing.T
+	to   string
+	base string
+}
+
+func newRepository(t *testing.T) *repository {
+	root := t.TempDir()
+	return &repository{
+		t:    t,
+		to:   filepath.Join(root, "clusters", "demo"),
+		base: filepath.Join(root, BaseDir, "clusters", "demo"),
+	}
+}
+
+// export syncs files, the manifests exported from the cluster, into the
+// repository
+func (r *repository) export(files map[string]string) *Result {
+	r.t.Helper()
+	from := r.t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(from, name), []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	result, err := Sync(from, r.to, Options{Merge: true, Base: r.base})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return result
+}
+
+// edit changes a file in the repository by hand
+func (r *repository) edit(name, old, new string) {
+	r.t.Helper()
+	content := r.read(name)
+	if !strings.Contains(content, old) {
+		r.t.Fatalf("%s does not contain %q", name, old)
+	}
+	if err := ioutil.WriteFile(filepath.Join(r.to, name), []byte(strings.Replace(content, old, new, 1)), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *repository) read(name string) string {
+	r.t.Helper()
+	return readContent(r.t, filepath.Join(r.to, name))
+}
+
+// readBase returns the merge base of a file
+func (r *repository) readBase(name string) string {
+	r.t.Helper()
+	return readContent(r.t, filepath.Join(r.base, name))
+}
+
+func (r *repository) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(r.to, name))
+	return err == nil
+}
+
+func (r *repository) baseExists(name string) bool {
+	_, err := os.Stat(filepath.Join(r.base, name))
+	return err == nil
+}
+
+func readContent(t *testing.T, path string) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestMergeKeepsRepositoryChanges(t *testing.T) {
+	r := newRepository(t)
+	r.export(map[string]string{settings: settingsManifest})
+	r.edit(settings, "c: three", "c: THREE")
+
+	// Nothing changed in the cluster
+	if result := r.export(map[string]string{settings: settingsManifest}); len(result.Conflicts) != 0 {
+		t.Errorf("conflicts = %v", result.Conflicts)
+	}
+	if got := r.read(settings); !strings.Contains(got, "c: THREE") {
+		t.Errorf("repository change was overwritten:\n%s", got)
+	}
+
+	// The cluster changed another line
+	result := r.export(map[string]string{settings: strings.Replace(settingsManifest, "a: one", "a: ONE", 1)})
+	if len(result.Conflicts) != 0 {
+		t.Errorf("conflicts = %v", result.Conflicts)
+	}
+	want := strings.NewReplacer("a: one", "a: ONE", "c: three", "c: THREE").Replace(settingsManifest)
+	if got := r.read(settings); got != want {
+		t.Errorf("merged =\n%s\nwant\n%s", got, want)
+	}
+	if got := r.readBase(settings); !strings.Contains(got, "a: ONE") || strings.Contains(got, "THREE") {
+		t.Errorf("base is not the last export:\n%s", got)
+	}
+}
+
+func TestMergeConflict(t *testing.T) {
+	r := newRepository(t)
+	r.export(map[string]string{settings: settingsManifest})
+	r.edit(settings, "a: one", "a: uno")
+
+	result := r.export(map[string]string{settings: strings.Replace(settingsManifest, "a: one", "a: ONE", 1)})
+	if !reflect.DeepEqual(result.Conflicts, []string{settings}) {
+		t.Fatalf("conflicts = %v", result.Conflicts)
+	}
+	if got := r.read(settings); !strings.Contains(got, "a: uno") {
+		t.Errorf("conflicting file was changed:\n%s", got)
+	}
+	if got := r.readBase(settings); got != settingsManifest {
+		t.Errorf("base moved on to an export that was not merged:\n%s", got)
+	}
+	// The file stays owned by the export until the conflict is resolved
+	if index, _ := ReadIndex(r.to); !reflect.DeepEqual(index, []string{settings}) {
+		t.Errorf("index = %v", index)
+	}
+}
+
+func TestMergeWithoutBase(t *testing.T) {
+	r := newRepository(t)
+	// Written by an export before merging was enabled, then edited
+	if err := os.MkdirAll(r.to, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(r.to, settings), []byte(strings.Replace(settingsManifest, "c: three", "c: hand edited", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := r.export(map[string]string{settings: settingsManifest})
+	if len(result.Conflicts) != 0 {
+		t.Errorf("conflicts = %v", result.Conflicts)
+	}
+	if got := r.read(settings); !strings.Contains(got, "c: hand edited") {
+		t.Errorf("file without a base was overwritten:\n%s", got)
+	}
+	if got := r.readBase(settings); got != settingsManifest {
+		t.Errorf("base was not seeded with the export:\n%s", got)
+	}
+
+	// From then on changes made in the cluster are merged in
+	r.export(map[string]string{settings: strings.Replace(settingsManifest, "a: one", "a: ONE", 1)})
+	want := strings.NewReplacer("a: one", "a: ONE", "c: three", "c: hand edited").Replace(settingsManifest)
+	if got := r.read(settings); got != want {
+		t.Errorf("merged =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeKeepsBaseOutsideTarget(t *testing.T) {
+	r := newRepository(t)
+	r.export(map[string]string{settings: settingsManifest})
+
+	// Everything in the target directory is applied to the cluster
+	files, err := listFiles(r.to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{IndexFile, settings}; !reflect.DeepEqual(files, want) {
+		t.Errorf("target directory holds %v, want only %v", files, want)
+	}
+	if !r.baseExists(settings) {
+		t.Errorf("no base written for %s", settings)
+	}
+
+	if _, err := Sync(t.TempDir(), r.to, Options{Merge: true}); err == nil {
+		t.Error("Sync merged without a base directory")
+	}
+}
+
+func TestMergePrune(t *testing.T) {
+	const web = "Service_v1_demo_web.yaml"
+	r := newRepository(t)
+	r.export(map[string]string{settings: settingsManifest, web: "kind: Service\nmetadata:\n  name: web\n"})
+	r.edit(settings, "b: two", "b: TWO")
+
+	// Both objects were deleted from the cluster
+	result := r.export(nil)
+	if !reflect.DeepEqual(result.Pruned, []string{web}) {
+		t.Errorf("pruned = %v", result.Pruned)
+	}
+	if !reflect.DeepEqual(result.Conflicts, []string{settings}) {
+		t.Errorf("conflicts = %v", result.Conflicts)
+	}
+	if r.exists(web) || r.baseExists(web) {
+		t.Errorf("%s or its base was not pruned", web)
+	}
+	if !r.exists(settings) {
+		t.Errorf("%s, changed in the repository, was pruned", settings)
+	}
+}
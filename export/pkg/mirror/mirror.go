@@ -0,0 +1,223 @@
+// Package mirror copies the manifests produced by crane into the target
+// directory of the repository. The files written are recorded in an index
+// so that the files of objects which no longer exist can be pruned on the
+// next export without touching files the export does not own. Paths
+// matching the IgnoreFile of the target directory are left alone. Changes
+// made to exported files in the repository can optionally be merged with
+// the new export rather than overwritten.
+package mirror
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexFile is the name of the index within the target directory
+const IndexFile = ".primer-index"
+
+const indexHeader = "# Files written by GitOps Primer, files listed here that are no longer\n# exported are deleted by the next export\n"
+
+// Result lists the files changed by Sync, relative to the target directory
+type Result struct {
+	Written []string
+	Pruned  []string
+	// Ignored lists exported files that were not written as they match
+	// the IgnoreFile
+	Ignored []string
+	// Conflicts lists files that were left as they are because the changes
+	// made in the repository conflict with the export
+	Conflicts []string
+}
+
+// Options change how Sync writes files
+type Options struct {
+	// Merge changes made to files in the repository since the last export
+	// with the new export instead of overwriting them
+	Merge bool
+	// Base is the directory the merge bases of the target directory are
+	// kept in, required when merging. It must be outside of the target
+	// directory so that the bases are not applied along with the manifests
+	Base string
+}
+
+// Sync copies every file in from into to and deletes the files listed in
+// the index of to that are not in from. Files matching the IgnoreFile of
+// to are neither written nor deleted
+func Sync(from, to string, opts Options) (*Result, error) {
+	if opts.Merge && opts.Base == "" {
+		return nil, fmt.Errorf("a base directory is required to merge")
+	}
+	if opts.Merge {
+		// Created up front so the bases can be committed along with the
+		// target directory even when nothing was exported
+		if err := os.MkdirAll(opts.Base, 0755); err != nil {
+			return nil, err
+		}
+	}
+	files, err := listFiles(from)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := ReadIndex(to)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := LoadIgnore(to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	exported := map[string]bool{}
+	for _, file := range files {
+		exported[file] = true
+		if file == IndexFile || file == IgnoreFile || ignore.Match(file) {
+			result.Ignored = append(result.Ignored, file)
+			continue
+		}
+		if opts.Merge {
+			conflict, err := merge(from, to, opts.Base, file)
+			if err != nil {
+				return nil, err
+			}
+			if conflict {
+				result.Conflicts = append(result.Conflicts, file)
+				continue
+			}
+		} else if err := copyFile(filepath.Join(from, file), filepath.Join(to, file)); err != nil {
+			return nil, err
+		}
+		result.Written = append(result.Written, file)
+	}
+	for _, file := range previous {
+		if exported[file] || ignore.Match(file) {
+			continue
+		}
+		conflict, err := prune(to, opts.Base, file, opts.Merge)
+		if err != nil {
+			return nil, err
+		}
+		if conflict {
+			result.Conflicts = append(result.Conflicts, file)
+			continue
+		}
+		result.Pruned = append(result.Pruned, file)
+	}
+	// Conflicting files stay owned by the export until they are resolved
+	return result, WriteIndex(to, append(result.Written, result.Conflicts...))
+}
+
+// ReadIndex returns the files listed in the index of dir
+func ReadIndex(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, IndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	files := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		file, err := cleanPath(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", IndexFile, err)
+		}
+		files = append(files, file)
+	}
+	return files, scanner.Err()
+}
+
+// WriteIndex records files as owned by the export in the index of dir
+func WriteIndex(dir string, files []string) error {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+	index := indexHeader
+	for _, file := range sorted {
+		index += file + "\n"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, IndexFile), []byte(index), 0644)
+}
+
+// cleanPath makes sure an index entry stays within the target directory
+func cleanPath(file string) (string, error) {
+	clean := filepath.ToSlash(filepath.Clean(file))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean == "." {
+		return "", fmt.Errorf("invalid path %q", file)
+	}
+	return clean, nil
+}
+
+// listFiles returns the files below dir relative to dir
+func listFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if os.IsNotExist(err) {
+		// Nothing was exported
+		return files, nil
+	}
+	return files, err
+}
+
+func copyFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// remove deletes file from dir along with any directories it leaves empty
+func remove(dir, file string) error {
+	path := filepath.Join(dir, filepath.FromSlash(file))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for parent := filepath.Dir(path); parent != filepath.Clean(dir); parent = filepath.Dir(parent) {
+		if err := os.Remove(parent); err != nil {
+			// Not empty or already gone
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates the files of tree, keyed by their path, below dir
+func writeTree(t *testing.T, dir string, tree map[string]string) {
+	t.Helper()
+	for name, content := range tree {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree returns the files below dir keyed by their path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files, err := listFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := map[string]string{}
+	for _, name := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree[name] = string(content)
+	}
+	return tree
+}
+
+// sync exports tree into to
+func sync(t *testing.T, to string, tree map[string]string) *Result {
+	t.Helper()
+	from := t.TempDir()
+	writeTree(t, from, tree)
+	result, err := Sync(from, to, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestSyncPrunes(t *testing.T) {
+	to := t.TempDir()
+	sync(t, to, map[string]string{
+		"Service_v1_demo_web.yaml":         "web",
+		"Service_v1_demo_api.yaml":         "api",
+		"charts/templates/Route_demo.yaml": "route",
+	})
+	writeTree(t, to, map[string]string{"README.md": "added by hand"})
+
+	result := sync(t, to, map[string]string{"Service_v1_demo_web.yaml": "web v2"})
+
+	if want := []string{"Service_v1_demo_web.yaml"}; !reflect.DeepEqual(result.Written, want) {
+		t.Errorf("written = %v, want %v", result.Written, want)
+	}
+	if want := []string{"Service_v1_demo_api.yaml", "charts/templates/Route_demo.yaml"}; !reflect.DeepEqual(result.Pruned, want) {
+		t.Errorf("pruned = %v, want %v", result.Pruned, want)
+	}
+	want := map[string]string{
+		"Service_v1_demo_web.yaml": "web v2",
+		"README.md":                "added by hand",
+		IndexFile:                  indexHeader + "Service_v1_demo_web.yaml\n",
+	}
+	if got := readTree(t, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("repository = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(to, "charts")); !os.IsNotExist(err) {
+		t.Errorf("empty directory of a pruned file was kept: %v", err)
+	}
+}
+
+func TestSyncIgnored(t *testing.T) {
+	to := t.TempDir()
+	sync(t, to, map[string]string{
+		"Secret_v1_demo_creds.yaml":    "exported",
+		"ConfigMap_v1_demo_flags.yaml": "flags",
+	})
+	// The Secret is managed by hand from now on, and a stale index entry
+	// that now matches the IgnoreFile must not be pruned either
+	writeTree(t, to, map[string]string{
+		IgnoreFile:                  "Secret_*\n",
+		"Secret_v1_demo_creds.yaml": "managed by hand",
+	})
+
+	result := sync(t, to, map[string]string{
+		"Secret_v1_demo_creds.yaml": "exported again",
+		IndexFile:                   "an index within the export",
+	})
+
+	if want := []string{IndexFile, "Secret_v1_demo_creds.yaml"}; !reflect.DeepEqual(result.Ignored, want) {
+		t.Errorf("ignored = %v, want %v", result.Ignored, want)
+	}
+	if want := []string{"ConfigMap_v1_demo_flags.yaml"}; !reflect.DeepEqual(result.Pruned, want) {
+		t.Errorf("pruned = %v, want %v", result.Pruned, want)
+	}
+	want := map[string]string{
+		IgnoreFile:                  "Secret_*\n",
+		"Secret_v1_demo_creds.yaml": "managed by hand",
+		IndexFile:                   indexHeader,
+	}
+	if got := readTree(t, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("repository = %v, want %v", got, want)
+	}
+}
+
+func TestSyncWithoutExport(t *testing.T) {
+	to := t.TempDir()
+	sync(t, to, map[string]string{"Service_v1_demo_web.yaml": "web"})
+
+	// A namespace with nothing left to export produces no directory at all
+	result, err := Sync(filepath.Join(t.TempDir(), "missing"), to, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Service_v1_demo_web.yaml"}; !reflect.DeepEqual(result.Pruned, want) {
+		t.Errorf("pruned = %v, want %v", result.Pruned, want)
+	}
+	if got, want := readTree(t, to), map[string]string{IndexFile: indexHeader}; !reflect.DeepEqual(got, want) {
+		t.Errorf("repository = %v, want %v", got, want)
+	}
+}
+
+func TestReadIndex(t *testing.T) {
+	dir := t.TempDir()
+	if files, err := ReadIndex(dir); err != nil || files != nil {
+		t.Errorf("ReadIndex without an index = %v, %v", files, err)
+	}
+
+	writeTree(t, dir, map[string]string{IndexFile: "# comment\n\n./a.yaml\n  b//c.yaml  \n"})
+	files, err := ReadIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.yaml", "b/c.yaml"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+
+	// An index edited to point outside the target directory must never
+	// lead to files elsewhere in the repository being deleted
+	for _, entry := range []string{"../other/a.yaml", "/etc/passwd", "."} {
+		writeTree(t, dir, map[string]string{IndexFile: entry + "\n"})
+		if _, err := ReadIndex(dir); err == nil {
+			t.Errorf("ReadIndex accepted %q", entry)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pullrequest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+func init() {
+	register("gitea", factory{
+		new: func(config Config) Provider { return &gitea{config} },
+		defaultAPIURL: func(host string) string {
+			return "https://" + host + "/api/v1"
+		},
+	})
+}
+
+// gitea opens pull requests through the Gitea API
+type gitea struct {
+	Config
+}
+
+// giteaPull is a pull request as returned by the API
+type giteaPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// giteaPageSize is the number of pull requests listed per request
+const giteaPageSize = 50
+
+func (g *gitea) Find(ctx context.Context, req Request) (*PullRequest, error) {
+	// The API cannot filter by branch, so page through the open pull
+	// requests
+	for page := 1; ; page++ {
+		out := []giteaPull{}
+		url := fmt.Sprintf("%s?state=open&limit=%d&page=%d", g.pullsURL(req), giteaPageSize, page)
+		if err := sendJSON(ctx, g.Client, http.MethodGet, url, g.header(), nil, &out); err != nil {
+			return nil, err
+		}
+		for _, pull := range out {
+			if pull.Head.Ref == req.Head && pull.Base.Ref == req.Base {
+				return &PullRequest{Number: pull.Number, URL: pull.HTMLURL}, nil
+			}
+		}
+		if len(out) < giteaPageSize {
+			return nil, nil
+		}
+	}
+}
+
+func (g *gitea) Open(ctx context.Context, req Request) (*PullRequest, error) {
+	in := map[string]string{
+		"title": req.Title,
+		"body":  req.Body,
+		"head":  req.Head,
+		"base":  req.Base,
+	}
+	out := giteaPull{}
+	if err := sendJSON(ctx, g.Client, http.MethodPost, g.pullsURL(req), g.header(), in, &out); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: out.Number, URL: out.HTMLURL}, nil
+}
+
+func (g *gitea) Update(ctx context.Context, pr *PullRequest, req Request) (*PullRequest, error) {
+	in := map[string]string{
+		"title": req.Title,
+		"body":  req.Body,
+	}
+	out := giteaPull{}
+	if err := sendJSON(ctx, g.Client, http.MethodPatch, fmt.Sprintf("%s/%d", g.pullsURL(req), pr.Number), g.header(), in, &out); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: out.Number, URL: out.HTMLURL}, nil
+}
+
+func (g *gitea) pullsURL(req Request) string {
+	return g.APIURL + "/repos/" + req.Repository + "/pulls"
+}
+
+func (g *gitea) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "token "+g.Token)
+	return header
+}
@@ -0,0 +1,84 @@
+package pullrequest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	register("github", factory{
+		new: func(config Config) Provider { return &github{config} },
+		defaultAPIURL: func(host string) string {
+			if host == "github.com" {
+				return "https://api.github.com"
+			}
+			// GitHub Enterprise Server
+			return "https://" + host + "/api/v3"
+		},
+	})
+}
+
+// github opens pull requests through the GitHub REST API
+type github struct {
+	Config
+}
+
+// githubPull is a pull request as returned by the API
+type githubPull struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (g *github) Find(ctx context.Context, req Request) (*PullRequest, error) {
+	// The head branch is qualified by the owner of the repository it is in
+	owner := strings.SplitN(req.Repository, "/", 2)[0]
+	query := url.Values{"state": {"open"}, "head": {owner + ":" + req.Head}, "base": {req.Base}}
+	out := []githubPull{}
+	if err := sendJSON(ctx, g.Client, http.MethodGet, g.pullsURL(req)+"?"+query.Encode(), g.header(), nil, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return &PullRequest{Number: out[0].Number, URL: out[0].HTMLURL}, nil
+}
+
+func (g *github) Open(ctx context.Context, req Request) (*PullRequest, error) {
+	in := map[string]string{
+		"title": req.Title,
+		"body":  req.Body,
+		"head":  req.Head,
+		"base":  req.Base,
+	}
+	out := githubPull{}
+	if err := sendJSON(ctx, g.Client, http.MethodPost, g.pullsURL(req), g.header(), in, &out); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: out.Number, URL: out.HTMLURL}, nil
+}
+
+func (g *github) Update(ctx context.Context, pr *PullRequest, req Request) (*PullRequest, error) {
+	in := map[string]string{
+		"title": req.Title,
+		"body":  req.Body,
+	}
+	out := githubPull{}
+	if err := sendJSON(ctx, g.Client, http.MethodPatch, fmt.Sprintf("%s/%d", g.pullsURL(req), pr.Number), g.header(), in, &out); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: out.Number, URL: out.HTMLURL}, nil
+}
+
+func (g *github) pullsURL(req Request) string {
+	return g.APIURL + "/repos/" + req.Repository + "/pulls"
+}
+
+func (g *github) header() http.Header {
+	header := http.Header{}
+	header.Set("Authorization", "token "+g.Token)
+	header.Set("Accept", "application/vnd.github.v3+json")
+	return header
+}
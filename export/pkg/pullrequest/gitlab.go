@@ -0,0 +1,77 @@
+package pullrequest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func init() {
+	register("gitlab", factory{
+		new: func(config Config) Provider { return &gitlab{config} },
+		defaultAPIURL: func(host string) string {
+			return "https://" + host + "/api/v4"
+		},
+	})
+}
+
+// gitlab opens merge requests through the GitLab REST API
+type gitlab struct {
+	Config
+}
+
+// gitlabMergeRequest is a merge request as returned by the API
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+func (g *gitlab) Find(ctx context.Context, req Request) (*PullRequest, error) {
+	query := url.Values{"state": {"opened"}, "source_branch": {req.Head}, "target_branch": {req.Base}}
+	out := []gitlabMergeRequest{}
+	if err := sendJSON(ctx, g.Client, http.MethodGet, g.mergeRequestsURL(req)+"?"+query.Encode(), g.header(), nil, &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return &PullRequest{Number: out[0].IID, URL: out[0].WebURL}, nil
+}
+
+func (g *gitlab) Open(ctx context.Context, req Request) (*PullRequest, error) {
+	in := map[string]string{
+		"title":         req.Title,
+		"description":   req.Body,
+		"source_branch": req.Head,
+		"target_branch": req.Base,
+	}
+	out := gitlabMergeRequest{}
+	if err := sendJSON(ctx, g.Client, http.MethodPost, g.mergeRequestsURL(req), g.header(), in, &out); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: out.IID, URL: out.WebURL}, nil
+}
+
+func (g *gitlab) Update(ctx context.Context, pr *PullRequest, req Request) (*PullRequest, error) {
+	in := map[string]string{
+		"title":       req.Title,
+		"description": req.Body,
+	}
+	out := gitlabMergeRequest{}
+	if err := sendJSON(ctx, g.Client, http.MethodPut, fmt.Sprintf("%s/%d", g.mergeRequestsURL(req), pr.Number), g.header(), in, &out); err != nil {
+		return nil, err
+	}
+	return &PullRequest{Number: out.IID, URL: out.WebURL}, nil
+}
+
+// mergeRequestsURL addresses the project by its URL encoded path
+func (g *gitlab) mergeRequestsURL(req Request) string {
+	return g.APIURL + "/projects/" + url.PathEscape(req.Repository) + "/merge_requests"
+}
+
+func (g *gitlab) header() http.Header {
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", g.Token)
+	return header
+}
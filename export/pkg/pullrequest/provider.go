@@ -0,0 +1,175 @@
+// Package pullrequest opens pull requests, or merge requests, on the git
+// hosting services an export can be pushed to. Each export pushes to a
+// branch of its own, so a pull request that is still open for the branch
+// is updated rather than opening another one.
+package pullrequest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Request describes the pull request to open
+type Request struct {
+	// Repository path, owner/name for GitHub and Gitea or the full
+	// project path for GitLab
+	Repository string
+	// Branch holding the changes
+	Head string
+	// Branch the changes should be merged into
+	Base  string
+	Title string
+	Body  string
+}
+
+// PullRequest is a pull request that has been opened
+type PullRequest struct {
+	Number int
+	URL    string
+}
+
+// Provider opens and updates pull requests on a git hosting service
+type Provider interface {
+	// Find returns the open pull request from the head into the base
+	// branch of req, or nil if there is none
+	Find(ctx context.Context, req Request) (*PullRequest, error)
+	// Open opens a new pull request
+	Open(ctx context.Context, req Request) (*PullRequest, error)
+	// Update sets the title and body of an open pull request to those of
+	// req
+	Update(ctx context.Context, pr *PullRequest, req Request) (*PullRequest, error)
+}
+
+// OpenOrUpdate updates the open pull request for the head branch of req
+// or opens one if there is none. It reports whether a pull request was
+// opened
+func OpenOrUpdate(ctx context.Context, p Provider, req Request) (*PullRequest, bool, error) {
+	pr, err := p.Find(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+	if pr != nil {
+		pr, err = p.Update(ctx, pr, req)
+		return pr, false, err
+	}
+	pr, err = p.Open(ctx, req)
+	return pr, err == nil, err
+}
+
+// Config is used to create a Provider
+type Config struct {
+	// Base URL of the API of the service
+	APIURL string
+	// Token used to authenticate with the API
+	Token string
+	// Client used for API requests, defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// factory creates a Provider from a Config
+type factory struct {
+	new func(Config) Provider
+	// defaultAPIURL returns the API URL for a repository on host
+	defaultAPIURL func(host string) string
+}
+
+var providers = map[string]factory{}
+
+// register makes a Provider available by name
+func register(name string, f factory) {
+	providers[name] = f
+}
+
+// Names returns the names of the registered providers
+func Names() []string {
+	names := []string{}
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the named Provider. When no API URL is configured the
+// default for the host of repoURL is used
+func New(name, repoURL string, config Config) (Provider, error) {
+	f, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown pull request provider %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	if config.APIURL == "" {
+		host, _, err := ParseRepoURL(repoURL)
+		if err != nil {
+			return nil, err
+		}
+		config.APIURL = f.defaultAPIURL(host)
+	}
+	config.APIURL = strings.TrimSuffix(config.APIURL, "/")
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return f.new(config), nil
+}
+
+var repoURLPatterns = []*regexp.Regexp{
+	// https://host[:port]/path and ssh://[user@]host[:port]/path
+	regexp.MustCompile(`^(?:https?|ssh)://(?:[^@/]+@)?([^:/]+)(?::[0-9]+)?/(.+)$`),
+	// [user@]host:path
+	regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`),
+}
+
+// ParseRepoURL returns the host and repository path of a git URL
+func ParseRepoURL(repoURL string) (host, repository string, err error) {
+	for _, pattern := range repoURLPatterns {
+		if m := pattern.FindStringSubmatch(repoURL); m != nil {
+			return m[1], strings.TrimSuffix(strings.Trim(m[2], "/"), ".git"), nil
+		}
+	}
+	return "", "", fmt.Errorf("unable to parse repository URL %q", repoURL)
+}
+
+// sendJSON sends in to url, unless it is nil, and decodes the response
+// into out
+func sendJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header = header.Clone()
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
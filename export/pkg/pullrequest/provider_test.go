@@ -0,0 +1,220 @@
+package pullrequest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// call is a request received by the fake API
+type call struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]string
+}
+
+// fakeAPI answers each request with the response for its method and path
+// and records the requests made
+func fakeAPI(t *testing.T, responses map[string]string) (*httptest.Server, *[]call) {
+	calls := []call{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := call{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery}
+		if data, _ := ioutil.ReadAll(r.Body); len(data) != 0 {
+			if err := json.Unmarshal(data, &c.Body); err != nil {
+				t.Errorf("invalid request body %q: %v", data, err)
+			}
+		}
+		calls = append(calls, c)
+		response, ok := responses[r.Method+" "+c.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestOpenOrUpdate(t *testing.T) {
+	req := Request{Repository: "team/apps", Head: "primer-export/demo/nightly", Base: "main", Title: "Export of demo", Body: "Changed 2 objects"}
+	tests := []struct {
+		name       string
+		provider   string
+		responses  map[string]string
+		want       *PullRequest
+		wantOpened bool
+		wantCalls  []call
+	}{
+		{
+			name:     "github opens",
+			provider: "github",
+			responses: map[string]string{
+				"GET /repos/team/apps/pulls":  `[]`,
+				"POST /repos/team/apps/pulls": `{"number": 7, "html_url": "https://github.example/team/apps/pull/7"}`,
+			},
+			want:       &PullRequest{Number: 7, URL: "https://github.example/team/apps/pull/7"},
+			wantOpened: true,
+			wantCalls: []call{
+				{Method: "GET", Path: "/repos/team/apps/pulls", Query: "base=main&head=team%3Aprimer-export%2Fdemo%2Fnightly&state=open"},
+				{Method: "POST", Path: "/repos/team/apps/pulls", Body: map[string]string{"title": req.Title, "body": req.Body, "head": req.Head, "base": req.Base}},
+			},
+		},
+		{
+			name:     "github updates",
+			provider: "github",
+			responses: map[string]string{
+				"GET /repos/team/apps/pulls":     `[{"number": 5, "html_url": "https://github.example/team/apps/pull/5"}]`,
+				"PATCH /repos/team/apps/pulls/5": `{"number": 5, "html_url": "https://github.example/team/apps/pull/5"}`,
+			},
+			want: &PullRequest{Number: 5, URL: "https://github.example/team/apps/pull/5"},
+			wantCalls: []call{
+				{Method: "GET", Path: "/repos/team/apps/pulls", Query: "base=main&head=team%3Aprimer-export%2Fdemo%2Fnightly&state=open"},
+				{Method: "PATCH", Path: "/repos/team/apps/pulls/5", Body: map[string]string{"title": req.Title, "body": req.Body}},
+			},
+		},
+		{
+			name:     "gitea opens",
+			provider: "gitea",
+			responses: map[string]string{
+				"GET /repos/team/apps/pulls":  `[{"number": 3, "html_url": "u3", "head": {"ref": "other"}, "base": {"ref": "main"}}]`,
+				"POST /repos/team/apps/pulls": `{"number": 4, "html_url": "https://gitea.example/team/apps/pulls/4"}`,
+			},
+			want:       &PullRequest{Number: 4, URL: "https://gitea.example/team/apps/pulls/4"},
+			wantOpened: true,
+			wantCalls: []call{
+				{Method: "GET", Path: "/repos/team/apps/pulls", Query: "state=open&limit=50&page=1"},
+				{Method: "POST", Path: "/repos/team/apps/pulls", Body: map[string]string{"title": req.Title, "body": req.Body, "head": req.Head, "base": req.Base}},
+			},
+		},
+		{
+			name:     "gitea updates",
+			provider: "gitea",
+			responses: map[string]string{
+				"GET /repos/team/apps/pulls":     `[{"number": 3, "html_url": "u3", "head": {"ref": "primer-export/demo/nightly"}, "base": {"ref": "main"}}]`,
+				"PATCH /repos/team/apps/pulls/3": `{"number": 3, "html_url": "https://gitea.example/team/apps/pulls/3"}`,
+			},
+			want: &PullRequest{Number: 3, URL: "https://gitea.example/team/apps/pulls/3"},
+			wantCalls: []call{
+				{Method: "GET", Path: "/repos/team/apps/pulls", Query: "state=open&limit=50&page=1"},
+				{Method: "PATCH", Path: "/repos/team/apps/pulls/3", Body: map[string]string{"title": req.Title, "body": req.Body}},
+			},
+		},
+		{
+			name:     "gitlab opens",
+			provider: "gitlab",
+			responses: map[string]string{
+				"GET /projects/team%2Fapps/merge_requests":  `[]`,
+				"POST /projects/team%2Fapps/merge_requests": `{"iid": 9, "web_url": "https://gitlab.example/team/apps/-/merge_requests/9"}`,
+			},
+			want:       &PullRequest{Number: 9, URL: "https://gitlab.example/team/apps/-/merge_requests/9"},
+			wantOpened: true,
+			wantCalls: []call{
+				{Method: "GET", Path: "/projects/team%2Fapps/merge_requests", Query: "source_branch=primer-export%2Fdemo%2Fnightly&state=opened&target_branch=main"},
+				{Method: "POST", Path: "/projects/team%2Fapps/merge_requests", Body: map[string]string{"title": req.Title, "description": req.Body, "source_branch": req.Head, "target_branch": req.Base}},
+			},
+		},
+		{
+			name:     "gitlab updates",
+			provider: "gitlab",
+			responses: map[string]string{
+				"GET /projects/team%2Fapps/merge_requests":   `[{"iid": 2, "web_url": "https://gitlab.example/team/apps/-/merge_requests/2"}]`,
+				"PUT /projects/team%2Fapps/merge_requests/2": `{"iid": 2, "web_url": "https://gitlab.example/team/apps/-/merge_requests/2"}`,
+			},
+			want: &PullRequest{Number: 2, URL: "https://gitlab.example/team/apps/-/merge_requests/2"},
+			wantCalls: []call{
+				{Method: "GET", Path: "/projects/team%2Fapps/merge_requests", Query: "source_branch=primer-export%2Fdemo%2Fnightly&state=opened&target_branch=main"},
+				{Method: "PUT", Path: "/projects/team%2Fapps/merge_requests/2", Body: map[string]string{"title": req.Title, "description": req.Body}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := fakeAPI(t, tt.responses)
+			provider, err := New(tt.provider, "git@git.example:team/apps.git", Config{APIURL: server.URL, Token: "secret"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pr, opened, err := OpenOrUpdate(context.Background(), provider, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(pr, tt.want) || opened != tt.wantOpened {
+				t.Errorf("got %+v opened %v, want %+v opened %v", pr, opened, tt.want, tt.wantOpened)
+			}
+			if !reflect.DeepEqual(*calls, tt.wantCalls) {
+				t.Errorf("calls = %+v\nwant %+v", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGiteaFindPages(t *testing.T) {
+	page := []map[string]interface{}{}
+	for i := 0; i < giteaPageSize; i++ {
+		page = append(page, map[string]interface{}{"number": i, "head": map[string]string{"ref": "other"}, "base": map[string]string{"ref": "main"}})
+	}
+	first, _ := json.Marshal(page)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Write(first)
+		case "2":
+			w.Write([]byte(`[{"number": 60, "html_url": "u60", "head": {"ref": "export"}, "base": {"ref": "main"}}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := New("gitea", "git@git.example:team/apps.git", Config{APIURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := provider.Find(context.Background(), Request{Repository: "team/apps", Head: "export", Base: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&PullRequest{Number: 60, URL: "u60"}); !reflect.DeepEqual(pr, want) {
+		t.Errorf("got %+v, want %+v", pr, want)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	server, _ := fakeAPI(t, map[string]string{})
+	provider, err := New("github", "git@git.example:team/apps.git", Config{APIURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := OpenOrUpdate(context.Background(), provider, Request{Repository: "team/apps"}); err == nil {
+		t.Error("expected an error for a 404 response")
+	}
+}
+
+func TestParseRepoURL(t *testing.T) {
+	tests := []struct {
+		url        string
+		host       string
+		repository string
+	}{
+		{url: "git@github.com:team/apps.git", host: "github.com", repository: "team/apps"},
+		{url: "ssh://git@gitea.example:2222/team/apps.git", host: "gitea.example", repository: "team/apps"},
+		{url: "https://gitlab.example/group/sub/apps", host: "gitlab.example", repository: "group/sub/apps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			host, repository, err := ParseRepoURL(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != tt.host || repository != tt.repository {
+				t.Errorf("got %s %s, want %s %s", host, repository, tt.host, tt.repository)
+			}
+		})
+	}
+}
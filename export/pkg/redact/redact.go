@@ -0,0 +1,115 @@
+// Package redact replaces the values of exported Secrets with placeholders.
+// The Secrets keep their name, type, labels and keys, so the repository
+// documents which credentials are needed without holding any of them.
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+)
+
+// ChecklistFile lists the redacted Secrets and the keys to fill in
+const ChecklistFile = "SECRETS.md"
+
+// Placeholder returns the value a key of a Secret is replaced with. It
+// only depends on the key, so an export of an unchanged Secret does not
+// change the repository
+func Placeholder(key string) string {
+	return "REDACTED:" + key
+}
+
+// Secret is a Secret that has been redacted
+type Secret struct {
+	Name string
+	Type string
+	Keys []string
+}
+
+// Redact replaces the values of every Secret manifest in dir with
+// placeholders and returns the Secrets redacted. The placeholders are
+// written to stringData as they are not base64 encoded
+func Redact(dir string) ([]Secret, error) {
+	secrets := []Secret{}
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".yaml" {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if o, err := manifest.Parse(data); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		} else if o.Kind != "Secret" {
+			return nil
+		}
+		o := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &o); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+
+		s := Secret{Type: "Opaque"}
+		if meta, ok := o["metadata"].(map[string]interface{}); ok {
+			s.Name, _ = meta["name"].(string)
+		}
+		if t, ok := o["type"].(string); ok && t != "" {
+			s.Type = t
+		}
+		placeholders := map[string]interface{}{}
+		for _, field := range []string{"data", "stringData"} {
+			values, _ := o[field].(map[string]interface{})
+			for key := range values {
+				placeholders[key] = Placeholder(key)
+			}
+			delete(o, field)
+		}
+		for key := range placeholders {
+			s.Keys = append(s.Keys, key)
+		}
+		sort.Strings(s.Keys)
+		if len(placeholders) != 0 {
+			o["stringData"] = placeholders
+		}
+
+		out, err := yaml.Marshal(o)
+		if err != nil {
+			return err
+		}
+		secrets = append(secrets, s)
+		return ioutil.WriteFile(file, out, info.Mode())
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+// Checklist returns the checklist of the values to fill in before the
+// redacted Secrets of namespace can be applied
+func Checklist(namespace string, secrets []Secret) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Secrets of %s\n\n", namespace)
+	b.WriteString("The values of these Secrets were redacted by the export and replaced\n")
+	b.WriteString("with `REDACTED:<key>` placeholders. Fill them in before the manifests\n")
+	b.WriteString("are applied.\n")
+	for _, s := range secrets {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.Name)
+		fmt.Fprintf(&b, "Type `%s`\n\n", s.Type)
+		if len(s.Keys) == 0 {
+			b.WriteString("No keys\n")
+		}
+		for _, key := range s.Keys {
+			fmt.Fprintf(&b, "- [ ] `%s`\n", key)
+		}
+	}
+	return b.Bytes()
+}
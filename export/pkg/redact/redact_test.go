@@ -0,0 +1,111 @@
+package redact
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeManifests(t *testing.T, manifests map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRedact(t *testing.T) {
+	large := strings.Repeat("a", 100*1024)
+	dir := writeManifests(t, map[string]string{
+		"Secret_v1_demo_creds.yaml":       "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ntype: kubernetes.io/basic-auth\ndata:\n  username: YWRtaW4=\nstringData:\n  password: hunter2\n",
+		"Secret_v1_demo_token.yaml":       "apiVersion: v1\nkind: Secret\nmetadata:\n  name: token\ndata:\n  token: dG9rZW4=\n",
+		"Secret_v1_demo_empty.yaml":       "apiVersion: v1\nkind: Secret\nmetadata:\n  name: empty\n",
+		"Secret_v1_demo_large.yaml":       "apiVersion: v1\ndata:\n  big: " + large + "\nkind: Secret\nmetadata:\n  name: large\n",
+		"ConfigMap_v1_demo_settings.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  password: hunter2\n",
+	})
+
+	secrets, err := Redact(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Secret{
+		{Name: "creds", Type: "kubernetes.io/basic-auth", Keys: []string{"password", "username"}},
+		{Name: "empty", Type: "Opaque"},
+		{Name: "large", Type: "Opaque", Keys: []string{"big"}},
+		{Name: "token", Type: "Opaque", Keys: []string{"token"}},
+	}
+	if !reflect.DeepEqual(secrets, want) {
+		t.Errorf("secrets = %+v, want %+v", secrets, want)
+	}
+
+	for name, want := range map[string]string{
+		"Secret_v1_demo_creds.yaml": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\nstringData:\n  password: REDACTED:password\n  username: REDACTED:username\ntype: kubernetes.io/basic-auth\n",
+		"Secret_v1_demo_token.yaml": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: token\nstringData:\n  token: REDACTED:token\n",
+		"Secret_v1_demo_empty.yaml": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: empty\n",
+		"Secret_v1_demo_large.yaml": "apiVersion: v1\nkind: Secret\nmetadata:\n  name: large\nstringData:\n  big: REDACTED:big\n",
+		// Only Secrets are redacted
+		"ConfigMap_v1_demo_settings.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  password: hunter2\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s =\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+// TestRedactUnchangedSecret redacts the same Secret on two exports, the
+// second must not change the repository
+func TestRedactUnchangedSecret(t *testing.T) {
+	const name = "Secret_v1_demo_creds.yaml"
+	var redacted []string
+	for _, value := range []string{"aHVudGVyMg==", "bmV3LXZhbHVl"} {
+		dir := writeManifests(t, map[string]string{name: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ndata:\n  password: " + value + "\n"})
+		if _, err := Redact(dir); err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		redacted = append(redacted, string(content))
+	}
+	if redacted[0] != redacted[1] {
+		t.Errorf("redacted Secrets differ:\n%s\n%s", redacted[0], redacted[1])
+	}
+}
+
+func TestRedactUnidentifiableSecret(t *testing.T) {
+	dir := writeManifests(t, map[string]string{"Secret.yaml": "kind: Secret\ndata:\n  password: aHVudGVyMg==\n"})
+	if _, err := Redact(dir); err == nil {
+		t.Error("expected an error for a Secret without a name")
+	}
+	// The Secret must not be left for the export to commit
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "Secret.yaml"))
+	if !strings.Contains(string(content), "aHVudGVyMg==") {
+		t.Errorf("Secret.yaml was changed:\n%s", content)
+	}
+}
+
+func TestChecklist(t *testing.T) {
+	got := string(Checklist("demo", []Secret{
+		{Name: "creds", Type: "kubernetes.io/basic-auth", Keys: []string{"password", "username"}},
+		{Name: "empty", Type: "Opaque"},
+	}))
+	want := "# Secrets of demo\n\n" +
+		"The values of these Secrets were redacted by the export and replaced\n" +
+		"with `REDACTED:<key>` placeholders. Fill them in before the manifests\n" +
+		"are applied.\n" +
+		"\n## creds\n\nType `kubernetes.io/basic-auth`\n\n- [ ] `password`\n- [ ] `username`\n" +
+		"\n## empty\n\nType `Opaque`\n\nNo keys\n"
+	if got != want {
+		t.Errorf("checklist =\n%s\nwant\n%s", got, want)
+	}
+}
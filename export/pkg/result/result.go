@@ -0,0 +1,186 @@
+// Package result hands the outcome of an export run back to the controller.
+// The push container of the export job writes a Result to its termination
+// message, which the controller reads from the Pod status once the job has
+// completed.
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultPath is where Kubernetes reads the termination message from
+const DefaultPath = "/dev/termination-log"
+
+// MaxSize is the size of the termination message Kubernetes keeps, anything
+// beyond it is cut off
+const MaxSize = 4096
+
+// Result is the outcome of an export run
+type Result struct {
+	// URL of the pull request opened for the export
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+	// Number of the pull request opened for the export
+	PullRequestNumber int `json:"pullRequestNumber,omitempty"`
+	// Commit pushed to the branch of the pull request
+	PullRequestHead string `json:"pullRequestHead,omitempty"`
+	// Fingerprint of the key the commits were signed with
+	SigningKeyFingerprint string `json:"signingKeyFingerprint,omitempty"`
+	// Files left unchanged as changes made to them in the repository
+	// conflict with the export
+	MergeConflicts []string `json:"mergeConflicts,omitempty"`
+	// Possible credentials that failed the export
+	SecretsDetected []string `json:"secretsDetected,omitempty"`
+	// Possible credentials that were redacted from the export
+	SecretsRedacted []string `json:"secretsRedacted,omitempty"`
+	// Changes a dry run would have committed
+	Diff *Diff `json:"diff,omitempty"`
+}
+
+// Diff lists the objects, or files, a dry run would have added, changed
+// and removed
+type Diff struct {
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	// Number of objects in each list, the lists may have been cut down
+	// to fit in the termination message
+	AddedCount   int `json:"addedCount,omitempty"`
+	ChangedCount int `json:"changedCount,omitempty"`
+	RemovedCount int `json:"removedCount,omitempty"`
+}
+
+// Path returns the file the Result is written to, which can be overridden
+// with RESULT_PATH when running outside of a Pod
+func Path() string {
+	if path := os.Getenv("RESULT_PATH"); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// Parse reads a Result from a termination message. An empty message is an
+// empty Result
+func Parse(message string) (*Result, error) {
+	r := &Result{}
+	if strings.TrimSpace(message) == "" {
+		return r, nil
+	}
+	if err := json.Unmarshal([]byte(message), r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Update applies update to the Result stored at path and writes it back,
+// so that each step of the export can add to the same Result
+func Update(path string, update func(*Result)) error {
+	message, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	r, err := Parse(string(message))
+	if err != nil {
+		return err
+	}
+	update(r)
+	r.Fit(MaxSize)
+	out, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// Fit cuts the lists of the Result down until it encodes to at most size
+// bytes, keeping as many entries of each list as possible. A list that
+// loses entries ends with one saying how many were left out, the counts of
+// a Diff keep track of them instead
+func (r *Result) Fit(size int) {
+	if encodedSize(r) <= size {
+		return
+	}
+	conflicts, conflictsOmitted := omitted(r.MergeConflicts)
+	detected, detectedOmitted := omitted(r.SecretsDetected)
+	redacted, redactedOmitted := omitted(r.SecretsRedacted)
+	var diff Diff
+	if r.Diff != nil {
+		diff = *r.Diff
+	}
+	apply := func(n int) {
+		r.MergeConflicts = cut(conflicts, conflictsOmitted, n)
+		r.SecretsDetected = cut(detected, detectedOmitted, n)
+		r.SecretsRedacted = cut(redacted, redactedOmitted, n)
+		if r.Diff != nil {
+			r.Diff = &Diff{
+				Added:        first(diff.Added, n),
+				Changed:      first(diff.Changed, n),
+				Removed:      first(diff.Removed, n),
+				AddedCount:   diff.AddedCount,
+				ChangedCount: diff.ChangedCount,
+				RemovedCount: diff.RemovedCount,
+			}
+		}
+	}
+
+	// Find the most entries each list can keep with a binary search
+	low, high := 0, 0
+	for _, list := range [][]string{conflicts, detected, redacted, diff.Added, diff.Changed, diff.Removed} {
+		if len(list) > high {
+			high = len(list)
+		}
+	}
+	for low < high {
+		n := (low + high + 1) / 2
+		apply(n)
+		if encodedSize(r) <= size {
+			low = n
+		} else {
+			high = n - 1
+		}
+	}
+	apply(low)
+}
+
+func encodedSize(r *Result) int {
+	out, _ := json.Marshal(r)
+	return len(out)
+}
+
+// omitted splits the entry counting the entries left out of a list, by an
+// earlier Fit, from the rest of the list
+func omitted(list []string) ([]string, int) {
+	if len(list) == 0 {
+		return list, 0
+	}
+	var n int
+	last := list[len(list)-1]
+	if _, err := fmt.Sscanf(last, "and %d more", &n); err == nil && last == more(n) {
+		return list[:len(list)-1], n
+	}
+	return list, 0
+}
+
+// cut returns the first n entries of a list followed by an entry counting
+// the entries left out, if any
+func cut(list []string, omitted int, n int) []string {
+	kept := first(list, n)
+	if left := len(list) - len(kept) + omitted; left != 0 {
+		return append(kept, more(left))
+	}
+	return kept
+}
+
+func first(list []string, n int) []string {
+	if len(list) <= n {
+		return list
+	}
+	return list[:n:n]
+}
+
+func more(n int) string {
+	return fmt.Sprintf("and %d more", n)
+}
@@ -0,0 +1,116 @@
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func entries(prefix string, n int) []string {
+	list := []string{}
+	for i := 0; i < n; i++ {
+		list = append(list, fmt.Sprintf("%s-%03d/%s", prefix, i, strings.Repeat("x", 40)))
+	}
+	return list
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		check  func(t *testing.T, r *Result)
+	}{
+		{
+			name:   "small result is unchanged",
+			result: Result{MergeConflicts: []string{"a.yaml"}, SecretsDetected: []string{"b.yaml: token"}},
+			check: func(t *testing.T, r *Result) {
+				if !reflect.DeepEqual(r.MergeConflicts, []string{"a.yaml"}) || !reflect.DeepEqual(r.SecretsDetected, []string{"b.yaml: token"}) {
+					t.Errorf("result changed: %+v", r)
+				}
+			},
+		},
+		{
+			name:   "lists are cut and count what was left out",
+			result: Result{MergeConflicts: entries("conflict", 100), SecretsRedacted: entries("redacted", 100)},
+			check: func(t *testing.T, r *Result) {
+				for _, list := range [][]string{r.MergeConflicts, r.SecretsRedacted} {
+					kept := len(list) - 1
+					if kept <= 0 || list[kept] != fmt.Sprintf("and %d more", 100-kept) {
+						t.Errorf("list not cut with a count: %v", list)
+					}
+				}
+			},
+		},
+		{
+			name:   "earlier counts are added to",
+			result: Result{MergeConflicts: append(entries("conflict", 200), "and 50 more")},
+			check: func(t *testing.T, r *Result) {
+				kept := len(r.MergeConflicts) - 1
+				if want := fmt.Sprintf("and %d more", 250-kept); r.MergeConflicts[kept] != want {
+					t.Errorf("got %q, want %q", r.MergeConflicts[kept], want)
+				}
+			},
+		},
+		{
+			name: "diff keeps its counts",
+			result: Result{Diff: &Diff{
+				Added:      entries("added", 150),
+				Removed:    []string{"removed"},
+				AddedCount: 150, RemovedCount: 1,
+			}},
+			check: func(t *testing.T, r *Result) {
+				if r.Diff.AddedCount != 150 || r.Diff.RemovedCount != 1 {
+					t.Errorf("counts changed: %+v", r.Diff)
+				}
+				if len(r.Diff.Added) == 0 || len(r.Diff.Added) == 150 || strings.HasPrefix(r.Diff.Added[len(r.Diff.Added)-1], "and ") {
+					t.Errorf("added not cut without a count: %d entries", len(r.Diff.Added))
+				}
+				if !reflect.DeepEqual(r.Diff.Removed, []string{"removed"}) {
+					t.Errorf("removed = %v", r.Diff.Removed)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.result
+			r.Fit(MaxSize)
+			out, err := json.Marshal(&r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) > MaxSize {
+				t.Errorf("result is %d bytes, want at most %d", len(out), MaxSize)
+			}
+			tt.check(t, &r)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result")
+	if err := Update(path, func(r *Result) { r.MergeConflicts = entries("conflict", 100) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(path, func(r *Result) { r.SecretsRedacted = entries("redacted", 100) }); err != nil {
+		t.Fatal(err)
+	}
+	message, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(message) > MaxSize {
+		t.Errorf("result is %d bytes, want at most %d", len(message), MaxSize)
+	}
+	r, err := Parse(string(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.MergeConflicts) == 0 || len(r.SecretsRedacted) == 0 {
+		t.Errorf("a list was dropped: %+v", r)
+	}
+}
@@ -0,0 +1,233 @@
+// Package scan looks for credentials left in the exported manifests outside
+// of Secrets, such as passwords in ConfigMaps, tokens in the env of a
+// Deployment or keys in annotations. Findings either fail the export or are
+// redacted.
+package scan
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+	"github.com/cooktheryan/gitops-primer/export/pkg/redact"
+)
+
+// Pattern matches a credential within a value. When the expression has a
+// capturing group only the group is redacted
+type Pattern struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+// BuiltinPatterns are always matched
+var BuiltinPatterns = []Pattern{
+	{Name: "private-key", Regex: `(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`},
+	{Name: "jwt", Regex: `\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`},
+	{Name: "aws-access-key-id", Regex: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-access-key", Regex: `(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`},
+	{Name: "password-assignment", Regex: `(?i)\b(?:password|passwd|pwd|secret|token|api_?key)["']?\s*[=:]\s*["']?([^\s"'$<{][^\s"']{3,})`},
+}
+
+// credentialName matches the names of env vars and ConfigMap keys that
+// usually hold credentials
+var credentialName = regexp.MustCompile(`(?i)(password|passwd|secret|token|api_?key|private_?key|credentials?)$`)
+
+// kinds holding secrets by design, these are handled by the Secret
+// options of the export rather than scanned
+var skippedKinds = map[string]bool{
+	"Secret":         true,
+	"SealedSecret":   true,
+	"ExternalSecret": true,
+}
+
+// Finding is a possible credential found in a manifest
+type Finding struct {
+	Object manifest.Object
+	// Field path of the value within the object
+	Field string
+	// Name of the pattern or rule that matched
+	Rule string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s (%s)", f.Object, f.Field, f.Rule)
+}
+
+type compiled struct {
+	name  string
+	regex *regexp.Regexp
+}
+
+// Scanner checks manifests against the built-in and additional patterns
+type Scanner struct {
+	patterns []compiled
+}
+
+// New returns a Scanner matching the built-in patterns and patterns
+func New(patterns []Pattern) (*Scanner, error) {
+	s := &Scanner{}
+	for i, p := range append(append([]Pattern{}, BuiltinPatterns...), patterns...) {
+		regex, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %v", p.Name, err)
+		}
+		name := p.Name
+		if name == "" {
+			name = "pattern-" + strconv.Itoa(i-len(BuiltinPatterns))
+		}
+		s.patterns = append(s.patterns, compiled{name: name, regex: regex})
+	}
+	return s, nil
+}
+
+// Dir scans every manifest in dir. With redact the values found are
+// replaced with placeholders in place
+func (s *Scanner) Dir(dir string, redactFindings bool) ([]Finding, error) {
+	findings := []Finding{}
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".yaml" {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		object, err := manifest.Parse(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		if skippedKinds[object.Kind] {
+			return nil
+		}
+		var o interface{}
+		if err := yaml.Unmarshal(data, &o); err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		found := []Finding{}
+		o = s.walk(o, nil, "value", object, &found, redactFindings)
+		findings = append(findings, found...)
+		if !redactFindings || len(found) == 0 {
+			return nil
+		}
+		out, err := yaml.Marshal(o)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(file, out, info.Mode())
+	})
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].String() < findings[j].String() })
+	return findings, err
+}
+
+// walk scans every string within o and returns o, redacted if asked to.
+// key names the value in its placeholder
+func (s *Scanner) walk(o interface{}, path []string, key string, object manifest.Object, found *[]Finding, redactFindings bool) interface{} {
+	switch v := o.(type) {
+	case map[string]interface{}:
+		// An env var with a literal value, name and value are siblings
+		name, env := v["name"].(string)
+		env = env && isEnv(path)
+		if env {
+			if value, ok := v["value"].(string); ok && literal(value) && credentialName.MatchString(name) {
+				*found = append(*found, Finding{Object: object, Field: fieldPath(append(path, "value")), Rule: "credential-env"})
+				if redactFindings {
+					v["value"] = redact.Placeholder(name)
+				}
+				return v
+			}
+		}
+		for k, value := range v {
+			keyPath := append(append([]string{}, path...), k)
+			if str, ok := value.(string); ok && object.Kind == "ConfigMap" && len(path) == 1 && path[0] == "data" &&
+				literal(str) && credentialName.MatchString(k) {
+				*found = append(*found, Finding{Object: object, Field: fieldPath(keyPath), Rule: "credential-configmap-key"})
+				if redactFindings {
+					v[k] = redact.Placeholder(k)
+				}
+				continue
+			}
+			childKey := k
+			if env {
+				childKey = name
+			}
+			v[k] = s.walk(value, keyPath, childKey, object, found, redactFindings)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = s.walk(value, append(append([]string{}, path...), "["+strconv.Itoa(i)+"]"), key, object, found, redactFindings)
+		}
+		return v
+	case string:
+		return s.match(v, path, key, object, found, redactFindings)
+	}
+	return o
+}
+
+// match checks a value against every pattern
+func (s *Scanner) match(value string, path []string, key string, object manifest.Object, found *[]Finding, redactFindings bool) string {
+	for _, p := range s.patterns {
+		// The secret is the capturing group of a match, when it has one
+		secrets := [][2]int{}
+		for _, m := range p.regex.FindAllStringSubmatchIndex(value, -1) {
+			start, end := m[0], m[1]
+			if len(m) > 3 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if literal(value[start:end]) {
+				secrets = append(secrets, [2]int{start, end})
+			}
+		}
+		if len(secrets) == 0 {
+			continue
+		}
+		*found = append(*found, Finding{Object: object, Field: fieldPath(path), Rule: p.name})
+		if !redactFindings {
+			continue
+		}
+		// Replace from the end so earlier indexes stay valid
+		for i := len(secrets) - 1; i >= 0; i-- {
+			value = value[:secrets[i][0]] + redact.Placeholder(key) + value[secrets[i][1]:]
+		}
+	}
+	return value
+}
+
+// isEnv reports whether path is an entry of the env of a container
+func isEnv(path []string) bool {
+	return len(path) >= 2 && path[len(path)-2] == "env"
+}
+
+// literal reports whether a value is set in the manifest rather than a
+// reference or a value that has already been redacted
+func literal(value string) bool {
+	return value != "" && !strings.HasPrefix(value, "REDACTED:") && !strings.HasPrefix(value, "$(")
+}
+
+// fieldPath formats a path like spec.containers[0].env[1].value, keys
+// containing dots or slashes are quoted in brackets
+func fieldPath(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		switch {
+		case strings.HasPrefix(p, "["):
+			b.WriteString(p)
+		case strings.ContainsAny(p, "./"):
+			b.WriteString("[" + p + "]")
+		default:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(p)
+		}
+	}
+	return b.String()
+}
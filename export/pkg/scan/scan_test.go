@@ -0,0 +1,135 @@
+package scan
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// demo copies testdata/demo, an export of a namespace with credentials
+// left in a Deployment, a ConfigMap and a Service, to a new directory
+func demo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files, err := filepath.Glob(filepath.Join("testdata", "demo", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.Base(file)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func scan(t *testing.T, dir string, patterns []Pattern, redactFindings bool) []string {
+	t.Helper()
+	scanner, err := New(patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := scanner.Dir(dir, redactFindings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	return got
+}
+
+var demoFindings = []string{
+	"ConfigMap/settings data.api_key (credential-configmap-key)",
+	"ConfigMap/settings data[app.properties] (password-assignment)",
+	"Deployment/web spec.template.spec.containers[0].env[0].value (credential-env)",
+	"Service/web metadata.annotations[example.com/key] (aws-access-key-id)",
+}
+
+func TestDirFindings(t *testing.T) {
+	dir := demo(t)
+	if got := scan(t, dir, nil, false); !reflect.DeepEqual(got, demoFindings) {
+		t.Errorf("findings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(demoFindings, "\n"))
+	}
+	// Without redacting the manifests are left as they are
+	for _, file := range []string{"ConfigMap_v1_demo_settings.yaml", "Deployment_apps_v1_demo_web.yaml"} {
+		got, _ := ioutil.ReadFile(filepath.Join(dir, file))
+		want, _ := ioutil.ReadFile(filepath.Join("testdata", "demo", file))
+		if string(got) != string(want) {
+			t.Errorf("%s was changed:\n%s", file, got)
+		}
+	}
+}
+
+func TestDirRedacts(t *testing.T) {
+	dir := demo(t)
+	if got := scan(t, dir, nil, true); !reflect.DeepEqual(got, demoFindings) {
+		t.Errorf("findings = %q, want %q", got, demoFindings)
+	}
+
+	settings, err := ioutil.ReadFile(filepath.Join(dir, "ConfigMap_v1_demo_settings.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the password of the assignment is replaced
+	want := "apiVersion: v1\ndata:\n  api_key: REDACTED:api_key\n  app.properties: |\n    user=admin\n    password=REDACTED:app.properties\n  mode: production\nkind: ConfigMap\nmetadata:\n  name: settings\n"
+	if string(settings) != want {
+		t.Errorf("settings =\n%s\nwant\n%s", settings, want)
+	}
+	web, err := ioutil.ReadFile(filepath.Join(dir, "Deployment_apps_v1_demo_web.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(web), "value: REDACTED:DB_PASSWORD") || !strings.Contains(string(web), "value: $(TOKEN)") {
+		t.Errorf("web =\n%s", web)
+	}
+	// Placeholders are not found again by the next scan
+	if got := scan(t, dir, nil, false); len(got) != 0 {
+		t.Errorf("findings after redacting = %q", got)
+	}
+}
+
+func TestDirAdditionalPattern(t *testing.T) {
+	got := scan(t, demo(t), []Pattern{{Regex: `internal-[A-Z]+-[0-9]+`}}, false)
+	if want := "ConfigMap/clean data.url (pattern-0)"; len(got) != len(demoFindings)+1 || got[0] != want {
+		t.Errorf("findings = %q, want %q first", got, want)
+	}
+}
+
+func TestDirLongValue(t *testing.T) {
+	dir := t.TempDir()
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: big\ndata:\n  blob: " + strings.Repeat("a", 100*1024) + "\n  token: abc123def\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "ConfigMap_v1_demo_big.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := scan(t, dir, nil, false), []string{"ConfigMap/big data.token (credential-configmap-key)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %q, want %q", got, want)
+	}
+}
+
+func TestDirUnidentifiableManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("kind: ConfigMap\ndata:\n  token: abc123def\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanner, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.Dir(dir, false); err == nil {
+		t.Error("expected an error for a manifest without a name")
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	if _, err := New([]Pattern{{Name: "broken", Regex: "("}}); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
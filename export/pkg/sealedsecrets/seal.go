@@ -0,0 +1,209 @@
+// Package sealedsecrets replaces exported Secrets with Bitnami
+// SealedSecrets, which only the sealed-secrets controller holding the
+// private key of the certificate can turn back into Secrets.
+package sealedsecrets
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+)
+
+// HashAnnotation records a salted hash of the Secret and certificate a
+// SealedSecret was sealed from. Sealing is not deterministic, so the hash
+// is what tells an unchanged Secret apart on the next export. scrypt keeps
+// the hash from making the values of the Secret any easier to guess
+const HashAnnotation = "primer.gitops.io/secret-hash"
+
+// Options configure Seal
+type Options struct {
+	// File holding the PEM encoded certificate of the controller
+	Certificate string
+	// Namespace of the Secrets, the exported manifests do not record it
+	// but a SealedSecret can only be unsealed in the namespace it was
+	// sealed for unless it is annotated to be namespace or cluster wide
+	Namespace string
+	// Directory holding the manifests written by the previous export.
+	// Secrets that have not changed keep their previous SealedSecret, so
+	// that an export without changes does not rewrite every SealedSecret
+	Previous string
+	// kubeseal binary, defaults to kubeseal on the PATH
+	Binary string
+}
+
+// Result lists the SealedSecrets written by Seal
+type Result struct {
+	// SealedSecrets sealed from their Secret
+	Sealed []string
+	// SealedSecrets kept from the previous export
+	Unchanged []string
+}
+
+// Seal replaces every Secret manifest in dir with a SealedSecret. The
+// Secret is removed as soon as it has been sealed
+func Seal(dir string, opts Options) (*Result, error) {
+	if opts.Binary == "" {
+		opts.Binary = "kubeseal"
+	}
+	cert, err := ioutil.ReadFile(opts.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	r := &Result{}
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".yaml" {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		o, err := manifest.Parse(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+		if o.Kind != "Secret" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filepath.Join(filepath.Dir(file), FileName(opts.Namespace, o.Name)))
+		if err != nil {
+			return err
+		}
+		out, ok := unchanged(data, cert, filepath.Join(opts.Previous, rel), opts)
+		if ok {
+			r.Unchanged = append(r.Unchanged, rel)
+		} else {
+			if out, err = kubeseal(data, opts); err == nil {
+				out, err = annotate(out, data, cert)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %v", filepath.Base(file), err)
+			}
+			r.Sealed = append(r.Sealed, rel)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, rel), out, info.Mode()); err != nil {
+			return err
+		}
+		return os.Remove(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FileName returns the name of the file a SealedSecret is written to,
+// named the same way as the other exported manifests
+func FileName(namespace, name string) string {
+	return strings.Join([]string{"SealedSecret", "bitnami.com", "v1alpha1", namespace, name}, "_") + ".yaml"
+}
+
+// unchanged returns the previous SealedSecret when it was sealed from the
+// same Secret with the same certificate
+func unchanged(secret, cert []byte, previousFile string, opts Options) ([]byte, bool) {
+	if opts.Previous == "" {
+		return nil, false
+	}
+	previous, err := ioutil.ReadFile(previousFile)
+	if err != nil {
+		return nil, false
+	}
+	var meta struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(previous, &meta); err != nil {
+		return nil, false
+	}
+	parts := strings.Split(meta.Metadata.Annotations[HashAnnotation], ":")
+	if len(parts) != 3 || parts[0] != "scrypt" {
+		return nil, false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false
+	}
+	got, err := hash(secret, cert, salt)
+	if err != nil {
+		return nil, false
+	}
+	return previous, subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// annotate records the hash of the Secret and certificate on a
+// SealedSecret
+func annotate(sealed, secret, cert []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	sum, err := hash(secret, cert, salt)
+	if err != nil {
+		return nil, err
+	}
+	o := map[string]interface{}{}
+	if err := yaml.Unmarshal(sealed, &o); err != nil {
+		return nil, err
+	}
+	meta, ok := o["metadata"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("kubeseal returned a SealedSecret without metadata")
+	}
+	annotations, _ := meta["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	annotations[HashAnnotation] = "scrypt:" + base64.StdEncoding.EncodeToString(salt) + ":" + base64.StdEncoding.EncodeToString(sum)
+	meta["annotations"] = annotations
+	return yaml.Marshal(o)
+}
+
+// hash returns the scrypt hash of a Secret, independent of the formatting
+// of its manifest, and the certificate it is sealed with
+func hash(secret, cert, salt []byte) ([]byte, error) {
+	o := map[string]interface{}{}
+	if err := yaml.Unmarshal(secret, &o); err != nil {
+		return nil, err
+	}
+	canonical, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return scrypt.Key(append(append(canonical, 0), cert...), salt, 1<<15, 8, 1, 32)
+}
+
+// kubeseal seals a Secret offline with the certificate. Names, labels and
+// annotations, including the scope annotations, are kept by kubeseal
+func kubeseal(secret []byte, opts Options) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(opts.Binary,
+		"--cert", opts.Certificate,
+		"--namespace", opts.Namespace,
+		"--format", "yaml",
+		"--allow-empty-data")
+	cmd.Stdin = bytes.NewReader(secret)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package sealedsecrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const secretManifest = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ndata:\n  password: aHVudGVyMg==\n"
+
+// fakeKubeseal stands in for kubeseal. Like kubeseal its output differs
+// every time it seals the same Secret
+const fakeKubeseal = `#!/bin/sh
+cat > /dev/null
+cat <<EOF
+apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: creds
+  namespace: demo
+spec:
+  encryptedData:
+    password: $(od -An -N16 -tx1 /dev/urandom | tr -d ' \n')
+EOF
+`
+
+// TestSealAcrossExports seals a Secret on successive exports, each handed
+// the SealedSecret written by the one before
+func TestSealAcrossExports(t *testing.T) {
+	tools := t.TempDir()
+	opts := Options{Certificate: filepath.Join(tools, "cert.pem"), Namespace: "demo", Binary: filepath.Join(tools, "kubeseal")}
+	if err := ioutil.WriteFile(opts.Binary, []byte(fakeKubeseal), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sealedName := FileName("demo", "creds")
+	var previous []byte
+
+	runs := []struct {
+		name   string
+		secret string
+		cert   string
+		// The SealedSecret of the previous export is kept
+		kept bool
+	}{
+		{name: "first export", secret: secretManifest, cert: "cert"},
+		{name: "unchanged", secret: secretManifest, cert: "cert", kept: true},
+		{name: "reformatted", secret: "kind: Secret\napiVersion: v1\ndata: {password: aHVudGVyMg==}\nmetadata: {name: creds}\n", cert: "cert", kept: true},
+		{name: "changed", secret: strings.Replace(secretManifest, "aHVudGVyMg==", "bmV3", 1), cert: "cert"},
+		{name: "rotated certificate", secret: strings.Replace(secretManifest, "aHVudGVyMg==", "bmV3", 1), cert: "new cert"},
+	}
+	for _, run := range runs {
+		if err := ioutil.WriteFile(opts.Certificate, []byte(run.cert), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(dir, "Secret_v1_demo_creds.yaml"), []byte(run.secret), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := Seal(dir, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", run.name, err)
+		}
+		if kept := len(result.Unchanged) == 1; kept != run.kept || len(result.Sealed)+len(result.Unchanged) != 1 {
+			t.Errorf("%s: result = %+v", run.name, *result)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "Secret_v1_demo_creds.yaml")); !os.IsNotExist(err) {
+			t.Errorf("%s: Secret was not removed", run.name)
+		}
+		sealed, err := ioutil.ReadFile(filepath.Join(dir, sealedName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept := string(sealed) == string(previous); kept != run.kept {
+			t.Errorf("%s: previous SealedSecret kept %v", run.name, kept)
+		}
+		checkAnnotation(t, sealed)
+		opts.Previous, previous = dir, sealed
+	}
+}
+
+// checkAnnotation makes sure a SealedSecret records the hash of its
+// Secret without holding the Secret itself
+func checkAnnotation(t *testing.T, sealed []byte) {
+	t.Helper()
+	var meta struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal(sealed, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if hash := meta.Metadata.Annotations[HashAnnotation]; !strings.HasPrefix(hash, "scrypt:") {
+		t.Errorf("%s = %q", HashAnnotation, hash)
+	}
+	if strings.Contains(string(sealed), "aHVudGVyMg==") {
+		t.Errorf("SealedSecret holds the Secret:\n%s", sealed)
+	}
+}
@@ -0,0 +1,183 @@
+// Package sops encrypts the data of exported Secrets with SOPS for age
+// recipients, so that Secrets can be committed to a repository and
+// decrypted by anyone, or any GitOps tool, holding a matching age identity.
+package sops
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cooktheryan/gitops-primer/export/pkg/manifest"
+)
+
+// ConfigFile is the SOPS configuration written next to the exported
+// manifests, so that the Secrets can be edited with sops in place
+const ConfigFile = ".sops.yaml"
+
+// EncryptedRegex selects the fields of a Secret that are encrypted, the
+// rest of the manifest stays readable
+const EncryptedRegex = "^(data|stringData)$"
+
+// Options configure Encrypt
+type Options struct {
+	// Age recipients the Secrets are encrypted for
+	Recipients []string
+	// Directory holding the manifests written by the previous export.
+	// Secrets that have not changed keep their previous ciphertext when
+	// it can be decrypted, so that an export without changes does not
+	// rewrite every Secret
+	Previous string
+	// File holding the age identities used to decrypt previous Secrets
+	IdentityFile string
+	// sops binary, defaults to sops on the PATH
+	Binary string
+}
+
+// Result lists the Secrets handled by Encrypt
+type Result struct {
+	// Secrets that were encrypted
+	Encrypted []string
+	// Secrets that kept their previous ciphertext
+	Unchanged []string
+}
+
+// ParseRecipients returns the age recipients listed in data, one or more
+// per line separated by commas. Blank lines and comments are skipped
+func ParseRecipients(data string) []string {
+	recipients := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, r := range strings.Split(line, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				recipients = append(recipients, r)
+			}
+		}
+	}
+	return recipients
+}
+
+// Encrypt encrypts every Secret manifest in dir in place and writes the
+// SOPS configuration for the recipients to dir
+func Encrypt(dir string, opts Options) (*Result, error) {
+	if len(opts.Recipients) == 0 {
+		return nil, fmt.Errorf("no age recipients to encrypt for")
+	}
+	if opts.Binary == "" {
+		opts.Binary = "sops"
+	}
+	r := &Result{}
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(file) != ".yaml" {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if kind, err := manifest.Kind(data); err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		} else if kind != "Secret" {
+			return nil
+		}
+		if previous, ok := unchanged(data, filepath.Join(opts.Previous, rel), opts); ok {
+			r.Unchanged = append(r.Unchanged, rel)
+			return ioutil.WriteFile(file, previous, info.Mode())
+		}
+		encrypted, err := run(opts, nil, "--encrypt",
+			"--age", strings.Join(opts.Recipients, ","),
+			"--encrypted-regex", EncryptedRegex,
+			"--input-type", "yaml", "--output-type", "yaml", file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		}
+		r.Encrypted = append(r.Encrypted, rel)
+		return ioutil.WriteFile(file, encrypted, info.Mode())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, ioutil.WriteFile(filepath.Join(dir, ConfigFile), Config(opts.Recipients), 0644)
+}
+
+// Config returns a SOPS configuration encrypting Secrets for recipients
+func Config(recipients []string) []byte {
+	return []byte(fmt.Sprintf(`creation_rules:
+- path_regex: .*\.yaml$
+  encrypted_regex: %s
+  age: %s
+`, EncryptedRegex, strings.Join(recipients, ",")))
+}
+
+// unchanged returns the previous ciphertext of a Secret when it was
+// encrypted for the same recipients and decrypts to the same object
+func unchanged(plain []byte, previousFile string, opts Options) ([]byte, bool) {
+	if opts.Previous == "" || opts.IdentityFile == "" {
+		return nil, false
+	}
+	previous, err := ioutil.ReadFile(previousFile)
+	if err != nil {
+		return nil, false
+	}
+	var meta struct {
+		SOPS struct {
+			Age []struct {
+				Recipient string `json:"recipient"`
+			} `json:"age"`
+		} `json:"sops"`
+	}
+	if err := yaml.Unmarshal(previous, &meta); err != nil {
+		return nil, false
+	}
+	recipients := []string{}
+	for _, a := range meta.SOPS.Age {
+		recipients = append(recipients, a.Recipient)
+	}
+	want := append([]string{}, opts.Recipients...)
+	sort.Strings(recipients)
+	sort.Strings(want)
+	if !reflect.DeepEqual(recipients, want) {
+		return nil, false
+	}
+
+	decrypted, err := run(opts, []string{"SOPS_AGE_KEY_FILE=" + opts.IdentityFile}, "--decrypt",
+		"--input-type", "yaml", "--output-type", "yaml", previousFile)
+	if err != nil {
+		return nil, false
+	}
+	var a, b interface{}
+	if yaml.Unmarshal(plain, &a) != nil || yaml.Unmarshal(decrypted, &b) != nil {
+		return nil, false
+	}
+	return previous, reflect.DeepEqual(a, b)
+}
+
+// run runs sops with args and returns its output
+func run(opts Options, env []string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(opts.Binary, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,236 @@
+package sops
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	secretManifest    = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: creds\ndata:\n  password: aHVudGVyMg==\n"
+	configMapManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: production\n"
+)
+
+// fakeSOPS stands in for sops. Encrypting appends a sops section listing
+// the recipients, decrypting removes it again
+const fakeSOPS = `#!/bin/sh
+for file; do :; done
+case "$1" in
+--encrypt)
+  cat "$file"
+  echo "sops:"
+  echo "  age:"
+  for r in $(echo "$3" | tr , ' '); do echo "  - recipient: $r"; done
+  ;;
+--decrypt)
+  test -f "$SOPS_AGE_KEY_FILE" || exit 1
+  sed '/^sops:/,$d' "$file"
+  ;;
+esac
+`
+
+func TestParseRecipients(t *testing.T) {
+	for data, want := range map[string][]string{
+		"":                    {},
+		"age1a\nage1b\n":      {"age1a", "age1b"},
+		"age1a, age1b,,age1c": {"age1a", "age1b", "age1c"},
+		"# team a\nage1a\n\n  # team b\n  age1b  \n": {"age1a", "age1b"},
+	} {
+		if got := ParseRecipients(data); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseRecipients(%q) = %q, want %q", data, got, want)
+		}
+	}
+}
+
+// exporter runs encrypting exports of Secrets with the fake sops
+type exporter struct {
+	t      *testing.T
+	binary string
+	// identity is an age identity file the previous export can be
+	// decrypted with
+	identity string
+	// previous holds the manifests written by the last export
+	previous string
+}
+
+func newExporter(t *testing.T) *exporter {
+	tools := t.TempDir()
+	e := &exporter{t: t, binary: filepath.Join(tools, "sops"), identity: filepath.Join(tools, "keys.txt")}
+	if err := ioutil.WriteFile(e.binary, []byte(fakeSOPS), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(e.identity, []byte("AGE-SECRET-KEY-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// export encrypts manifests for recipients, handing the last export to
+// Encrypt, and returns the result and the directory written
+func (e *exporter) export(manifests map[string]string, recipients ...string) (*Result, string) {
+	e.t.Helper()
+	dir := e.t.TempDir()
+	writeAll(e.t, dir, manifests)
+	result, err := Encrypt(dir, Options{Recipients: recipients, Previous: e.previous, IdentityFile: e.identity, Binary: e.binary})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	e.previous = dir
+	return result, dir
+}
+
+func TestEncrypt(t *testing.T) {
+	e := newExporter(t)
+	result, dir := e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest, "ConfigMap_v1_demo_settings.yaml": configMapManifest}, "age1a")
+
+	if want := (Result{Encrypted: []string{"Secret_v1_demo_creds.yaml"}}); !reflect.DeepEqual(*result, want) {
+		t.Errorf("result = %+v, want %+v", *result, want)
+	}
+	encrypted, _ := ioutil.ReadFile(filepath.Join(dir, "Secret_v1_demo_creds.yaml"))
+	if !strings.Contains(string(encrypted), "recipient: age1a") {
+		t.Errorf("Secret is not encrypted:\n%s", encrypted)
+	}
+	if got, _ := ioutil.ReadFile(filepath.Join(dir, "ConfigMap_v1_demo_settings.yaml")); string(got) != configMapManifest {
+		t.Errorf("ConfigMap was changed:\n%s", got)
+	}
+	config, err := ioutil.ReadFile(filepath.Join(dir, ConfigFile))
+	if err != nil || string(config) != string(Config([]string{"age1a"})) {
+		t.Errorf("%s = %q, %v", ConfigFile, config, err)
+	}
+}
+
+// TestEncryptUnchangedSecret exports the same Secret twice, the second
+// export keeps the ciphertext of the first even though the recipients
+// are listed in another order
+func TestEncryptUnchangedSecret(t *testing.T) {
+	e := newExporter(t)
+	_, first := e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1b", "age1a")
+	result, second := e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1a", "age1b")
+
+	if want := []string{"Secret_v1_demo_creds.yaml"}; !reflect.DeepEqual(result.Unchanged, want) || len(result.Encrypted) != 0 {
+		t.Errorf("result = %+v, want %v unchanged", *result, want)
+	}
+	before, _ := ioutil.ReadFile(filepath.Join(first, "Secret_v1_demo_creds.yaml"))
+	after, _ := ioutil.ReadFile(filepath.Join(second, "Secret_v1_demo_creds.yaml"))
+	if string(before) != string(after) {
+		t.Errorf("ciphertext changed:\n%s\n%s", before, after)
+	}
+}
+
+func TestEncryptChangedSecret(t *testing.T) {
+	changed := map[string]string{"Secret_v1_demo_creds.yaml": strings.Replace(secretManifest, "aHVudGVyMg==", "b2xk", 1)}
+	t.Run("data", func(t *testing.T) {
+		e := newExporter(t)
+		e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1a")
+		if result, _ := e.export(changed, "age1a"); len(result.Encrypted) != 1 {
+			t.Errorf("result = %+v, want the Secret encrypted", *result)
+		}
+	})
+	t.Run("recipients", func(t *testing.T) {
+		e := newExporter(t)
+		e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1a")
+		if result, _ := e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1a", "age1c"); len(result.Encrypted) != 1 {
+			t.Errorf("result = %+v, want the Secret encrypted", *result)
+		}
+	})
+	t.Run("no identity", func(t *testing.T) {
+		e := newExporter(t)
+		e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1a")
+		e.identity = ""
+		if result, _ := e.export(map[string]string{"Secret_v1_demo_creds.yaml": secretManifest}, "age1a"); len(result.Encrypted) != 1 {
+			t.Errorf("result = %+v, want the Secret encrypted", *result)
+		}
+	})
+}
+
+func TestEncryptErrors(t *testing.T) {
+	e := newExporter(t)
+	dir := t.TempDir()
+	writeAll(t, dir, map[string]string{"Secret_v1_demo_creds.yaml": secretManifest})
+	if _, err := Encrypt(dir, Options{Binary: e.binary}); err == nil {
+		t.Error("expected an error without recipients")
+	}
+
+	dir = t.TempDir()
+	writeAll(t, dir, map[string]string{"broken.yaml": "kind: Secret\nmetadata: [\n"})
+	if _, err := Encrypt(dir, Options{Recipients: []string{"age1a"}, Binary: e.binary}); err == nil {
+		t.Error("expected an error for a manifest that cannot be parsed")
+	}
+}
+
+// TestEncryptRoundTrip encrypts with sops for a generated age key and
+// decrypts the Secret again
+func TestEncryptRoundTrip(t *testing.T) {
+	for _, tool := range []string{"sops", "age-keygen"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+	dir, keys := t.TempDir(), t.TempDir()
+	identity := filepath.Join(keys, "keys.txt")
+	if out, err := exec.Command("age-keygen", "-o", identity).CombinedOutput(); err != nil {
+		t.Fatalf("age-keygen: %v: %s", err, out)
+	}
+	recipient, err := exec.Command("age-keygen", "-y", identity).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeAll(t, dir, map[string]string{"Secret_v1_demo_creds.yaml": secretManifest})
+	opts := Options{Recipients: ParseRecipients(string(recipient))}
+	if _, err := Encrypt(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "Secret_v1_demo_creds.yaml")
+	encrypted, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encrypted), "aHVudGVyMg==") {
+		t.Fatalf("Secret is not encrypted:\n%s", encrypted)
+	}
+	cmd := exec.Command("sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", file)
+	cmd.Env = append(os.Environ(), "SOPS_AGE_KEY_FILE="+identity)
+	decrypted, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want interface{}
+	if err := yaml.Unmarshal(decrypted, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(secretManifest), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decrypted Secret =\n%s\nwant\n%s", decrypted, secretManifest)
+	}
+
+	// Exporting the same Secret again keeps the ciphertext
+	previous := t.TempDir()
+	writeAll(t, previous, map[string]string{"Secret_v1_demo_creds.yaml": string(encrypted)})
+	writeAll(t, dir, map[string]string{"Secret_v1_demo_creds.yaml": secretManifest})
+	opts.Previous, opts.IdentityFile = previous, identity
+	result, err := Encrypt(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Unchanged, []string{"Secret_v1_demo_creds.yaml"}) {
+		t.Errorf("result = %+v, want the Secret unchanged", result)
+	}
+}
+
+func writeAll(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package watch
+
+import (
+	"sort"
+	"sync"
+)
+
+// Changes collects the objects reported by a watch until they are taken.
+// Reporting never blocks the informers, an object reported again before
+// it is taken is only kept once
+type Changes struct {
+	mu      sync.Mutex
+	pending map[string]bool
+	ready   chan struct{}
+}
+
+// NewChanges returns an empty set of changes
+func NewChanges() *Changes {
+	return &Changes{pending: map[string]bool{}, ready: make(chan struct{}, 1)}
+}
+
+// Add reports a change to object
+func (c *Changes) Add(object string) {
+	c.mu.Lock()
+	c.pending[object] = true
+	c.mu.Unlock()
+	select {
+	case c.ready <- struct{}{}:
+	default:
+		// Already signalled and not yet taken
+	}
+}
+
+// Ready receives a value when changes are waiting to be taken
+func (c *Changes) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Take returns the objects changed since the last call, in order
+func (c *Changes) Take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	objects := make([]string, 0, len(c.pending))
+	for object := range c.pending {
+		objects = append(objects, object)
+	}
+	sort.Strings(objects)
+	c.pending = map[string]bool{}
+	return objects
+}
@@ -0,0 +1,66 @@
+package watch
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+// Debounce collects changes until none have arrived for quiet, or maxDelay
+// has passed since the first of them, and then calls sync with the objects
+// that changed. Changes that arrive while sync runs are collected for the
+// next call. When sync fails the same objects are tried again after
+// maxDelay along with anything that changed in the meantime
+func Debounce(ctx context.Context, changes *Changes, quiet, maxDelay time.Duration, sync func(objects []string) error) {
+	pending := map[string]bool{}
+	var first time.Time
+	timer := time.NewTimer(quiet)
+	timer.Stop()
+	reset := func(d time.Duration) {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(d)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-changes.Ready():
+			objects := changes.Take()
+			if len(objects) == 0 {
+				continue
+			}
+			if len(pending) == 0 {
+				first = time.Now()
+			}
+			for _, object := range objects {
+				pending[object] = true
+			}
+			wait := quiet
+			if remaining := time.Until(first.Add(maxDelay)); remaining < wait {
+				wait = remaining
+			}
+			reset(wait)
+		case <-timer.C:
+			objects := []string{}
+			for object := range pending {
+				objects = append(objects, object)
+			}
+			sort.Strings(objects)
+			if err := sync(objects); err != nil {
+				log.Printf("Sync failed, retrying in %s: %v", maxDelay, err)
+				first = time.Now()
+				timer.Reset(maxDelay)
+				continue
+			}
+			pending = map[string]bool{}
+		}
+	}
+}
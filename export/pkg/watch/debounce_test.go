@@ -0,0 +1,141 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// debouncer runs Debounce in the background, recording the objects of
+// each sync. fail is returned by the sync calls until it is drained
+type debouncer struct {
+	changes *Changes
+	syncs   chan []string
+	fail    chan error
+}
+
+func startDebounce(t *testing.T, quiet, maxDelay time.Duration) *debouncer {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &debouncer{changes: NewChanges(), syncs: make(chan []string, 10), fail: make(chan error, 10)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Debounce(ctx, d.changes, quiet, maxDelay, func(objects []string) error {
+			d.syncs <- objects
+			select {
+			case err := <-d.fail:
+				return err
+			default:
+				return nil
+			}
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d
+}
+
+// next returns the objects of the next sync, failing when there is none
+// within timeout
+func (d *debouncer) next(t *testing.T, timeout time.Duration) []string {
+	t.Helper()
+	select {
+	case objects := <-d.syncs:
+		return objects
+	case <-time.After(timeout):
+		t.Fatalf("no sync within %s", timeout)
+		return nil
+	}
+}
+
+func (d *debouncer) none(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case objects := <-d.syncs:
+		t.Fatalf("unexpected sync of %v", objects)
+	case <-time.After(wait):
+	}
+}
+
+func TestDebounceCollectsChanges(t *testing.T) {
+	d := startDebounce(t, 50*time.Millisecond, time.Second)
+	d.changes.Add("Service/web")
+	d.changes.Add("ConfigMap/settings")
+	d.changes.Add("Service/web")
+
+	if got, want := d.next(t, time.Second), []string{"ConfigMap/settings", "Service/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("synced %v, want %v", got, want)
+	}
+	d.none(t, 150*time.Millisecond)
+
+	d.changes.Add("Service/web")
+	if got, want := d.next(t, time.Second), []string{"Service/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("synced %v, want %v", got, want)
+	}
+}
+
+func TestDebounceMaxDelay(t *testing.T) {
+	d := startDebounce(t, 100*time.Millisecond, 300*time.Millisecond)
+
+	// The namespace never goes quiet for long enough
+	stop := time.After(2 * time.Second)
+	start := time.Now()
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case objects := <-d.syncs:
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("changes were held back for %s", elapsed)
+			}
+			if len(objects) == 0 {
+				t.Error("synced without objects")
+			}
+			return
+		case <-ticker.C:
+			d.changes.Add("ConfigMap/settings")
+		case <-stop:
+			t.Fatal("no sync while the namespace kept changing")
+		}
+	}
+}
+
+func TestDebounceRetries(t *testing.T) {
+	d := startDebounce(t, 20*time.Millisecond, 200*time.Millisecond)
+	d.fail <- errors.New("push rejected")
+	d.changes.Add("Service/web")
+
+	if got, want := d.next(t, time.Second), []string{"Service/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("synced %v, want %v", got, want)
+	}
+	// The failed objects are tried again along with later changes
+	d.changes.Add("ConfigMap/settings")
+	if got, want := d.next(t, time.Second), []string{"ConfigMap/settings", "Service/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("retried %v, want %v", got, want)
+	}
+	d.none(t, 300*time.Millisecond)
+}
+
+func TestChanges(t *testing.T) {
+	c := NewChanges()
+	// Reporting never blocks, however many changes are waiting
+	for i := 0; i < 1000; i++ {
+		c.Add("ConfigMap/settings")
+		c.Add("Service/web")
+	}
+	select {
+	case <-c.Ready():
+	default:
+		t.Fatal("changes are not ready")
+	}
+	if got, want := c.Take(), []string{"ConfigMap/settings", "Service/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("took %v, want %v", got, want)
+	}
+	if got := c.Take(); len(got) != 0 {
+		t.Errorf("took %v again", got)
+	}
+}
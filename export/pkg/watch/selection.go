@@ -0,0 +1,88 @@
+package watch
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// selection decides which objects are exported the same way as the
+// ResourceSelectionPlugin, so that only changes to objects that end up in
+// the export set one off
+type selection struct {
+	ignored map[schema.GroupKind]bool
+	include map[schema.GroupKind]bool
+	// selector is nil when objects are not selected by label
+	selector labels.Selector
+	objects  map[schema.GroupKind]map[string]bool
+}
+
+func newSelection(opts Options) (*selection, error) {
+	s := &selection{
+		ignored: map[schema.GroupKind]bool{},
+		include: map[schema.GroupKind]bool{},
+		objects: map[schema.GroupKind]map[string]bool{},
+	}
+	for _, kind := range append(append([]string{}, Ignored...), opts.Exclude...) {
+		s.ignored[schema.ParseGroupKind(kind)] = true
+	}
+	for _, kind := range opts.Include {
+		s.include[schema.ParseGroupKind(kind)] = true
+	}
+	if opts.LabelSelector != "" {
+		selector, err := labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector: %v", err)
+		}
+		s.selector = selector
+	}
+	for _, o := range opts.Objects {
+		i := strings.LastIndex(o, "/")
+		if i == -1 {
+			continue
+		}
+		gk := schema.ParseGroupKind(o[:i])
+		if s.objects[gk] == nil {
+			s.objects[gk] = map[string]bool{}
+		}
+		s.objects[gk][o[i+1:]] = true
+	}
+	return s, nil
+}
+
+// watchesKind reports whether any object of a kind can be exported
+func (s *selection) watchesKind(gk schema.GroupKind) bool {
+	switch {
+	case s.ignored[gk]:
+		return false
+	case len(s.objects[gk]) != 0:
+		return true
+	case len(s.include) == 0 && s.selector == nil:
+		// Only named objects were asked for
+		return len(s.objects) == 0
+	case len(s.include) != 0:
+		return s.include[gk]
+	}
+	return true
+}
+
+// selects reports whether an object is exported. Excluded kinds always
+// win, named objects are always exported and everything else has to match
+// both the included kinds and the label selector when they are set
+func (s *selection) selects(gk schema.GroupKind, name string, objectLabels map[string]string) bool {
+	switch {
+	case s.ignored[gk]:
+		return false
+	case s.objects[gk][name]:
+		return true
+	case len(s.include) == 0 && s.selector == nil:
+		return len(s.objects) == 0
+	case len(s.include) != 0 && !s.include[gk]:
+		return false
+	case s.selector != nil:
+		return s.selector.Matches(labels.Set(objectLabels))
+	}
+	return true
+}
@@ -0,0 +1,298 @@
+// Package watch follows the objects of a namespace with dynamic informers
+// and reports the ones that change, so that a continuous export can commit
+// changes shortly after they are made.
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Ignored are the kinds that are never exported, as the transform plugins
+// white them out, or that change constantly without anything being changed
+// in the namespace. Kinds are given as Kind.group
+var Ignored = []string{
+	"Build.build.openshift.io",
+	"ClusterServiceVersion.operators.coreos.com",
+	"Endpoints",
+	"EndpointSlice.discovery.k8s.io",
+	"Event",
+	"Event.events.k8s.io",
+	"ImageStreamTag.image.openshift.io",
+	"ImageTag.image.openshift.io",
+	"Ingress.networking.internal.knative.dev",
+	"Lease.coordination.k8s.io",
+	"Metric.autoscaling.internal.knative.dev",
+	"PipelineRun.tekton.dev",
+	"Pod",
+	"PodAutoscaler.autoscaling.internal.knative.dev",
+	"ReplicaSet.apps",
+	"ReplicationController",
+	"Revision.serving.knative.dev",
+	"Route.serving.knative.dev",
+	"ServerlessService.networking.internal.knative.dev",
+	"TaskRun.tekton.dev",
+}
+
+// DefaultDiscoveryInterval is how often the API server is asked for new
+// resources, such as those of a CRD installed after the watch started
+const DefaultDiscoveryInterval = time.Minute
+
+// Options of a watch
+type Options struct {
+	// Namespace to watch
+	Namespace string
+	// Kinds not to watch in addition to Ignored, as Kind.group
+	Exclude []string
+	// Kinds to watch, as Kind.group, all kinds are watched when empty
+	Include []string
+	// LabelSelector the objects watched have to match
+	LabelSelector string
+	// Objects watched whatever their kind and labels, as Kind.group/name
+	Objects []string
+	// How often to look for new resources, defaults to
+	// DefaultDiscoveryInterval
+	DiscoveryInterval time.Duration
+}
+
+// Watch follows the namespaced resources that can be listed and watched in
+// the namespace until ctx is done, adding Kind/name to changes for each
+// object that is added, deleted or has its metadata or spec changed. Only
+// objects selected for the export by opts are reported, as well as those
+// that stop being selected. Updates to the status alone are not reported.
+// Objects that exist when the watch starts are not reported either, they
+// were exported by the run preceding the watch, but those of resources
+// discovered later are
+func Watch(ctx context.Context, config *rest.Config, opts Options, changes *Changes) error {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	return watch(ctx, discoveryClient, client, opts, changes, nil)
+}
+
+// resourceLister is the part of the discovery client used by the watch
+type resourceLister interface {
+	ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error)
+}
+
+// watch runs Watch with the given clients. synced is called, when set,
+// once the objects that existed when the watch started have been listed
+func watch(ctx context.Context, resources resourceLister, client dynamic.Interface, opts Options, changes *Changes, synced func()) error {
+	if opts.DiscoveryInterval == 0 {
+		opts.DiscoveryInterval = DefaultDiscoveryInterval
+	}
+	selection, err := newSelection(opts)
+	if err != nil {
+		return err
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, opts.Namespace, nil)
+	watched := map[schema.GroupVersionResource]bool{}
+	first := true
+	for {
+		discovered, err := discover(resources, selection)
+		if err != nil && len(discovered) == 0 {
+			if first {
+				return err
+			}
+			log.Printf("Unable to discover resources: %v", err)
+		} else if err != nil {
+			// An aggregated API that is unavailable should not stop the
+			// rest of the namespace being watched
+			log.Printf("Some resources could not be discovered: %v", err)
+		}
+
+		var existing []*initialList
+		added := 0
+		for gvr, gk := range discovered {
+			if watched[gvr] {
+				continue
+			}
+			watched[gvr] = true
+			informer := factory.ForResource(gvr).Informer()
+			var initial *initialList
+			if first {
+				initial = &initialList{informer: informer}
+				existing = append(existing, initial)
+			}
+			informer.AddEventHandler(handler(gk, selection, initial, changes))
+			added++
+		}
+		if added != 0 {
+			log.Printf("Watching %d new resources in namespace %s", added, opts.Namespace)
+			factory.Start(ctx.Done())
+		}
+		if first {
+			// Each informer is waited for on its own, one that cannot list
+			// its resource must not hold up the others
+			var wg sync.WaitGroup
+			for _, initial := range existing {
+				wg.Add(1)
+				go func(initial *initialList) {
+					defer wg.Done()
+					initial.wait(ctx)
+				}(initial)
+			}
+			go func() {
+				wg.Wait()
+				if ctx.Err() == nil && synced != nil {
+					synced()
+				}
+			}()
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.DiscoveryInterval):
+		}
+	}
+}
+
+// discover returns the kind of each resource of a namespace that can be
+// listed and watched and may hold objects selected for the export.
+// Resources are returned along with an error when only some groups could
+// be discovered
+func discover(client resourceLister, selection *selection) (map[schema.GroupVersionResource]schema.GroupKind, error) {
+	lists, err := client.ServerPreferredNamespacedResources()
+	resources := map[schema.GroupVersionResource]schema.GroupKind{}
+	for _, list := range lists {
+		gv, parseErr := schema.ParseGroupVersion(list.GroupVersion)
+		if parseErr != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			gk := schema.GroupKind{Group: gv.Group, Kind: r.Kind}
+			if !selection.watchesKind(gk) || !contains(r.Verbs, "list") || !contains(r.Verbs, "watch") {
+				continue
+			}
+			resources[gv.WithResource(r.Name)] = gk
+		}
+	}
+	return resources, err
+}
+
+// initialList tells the objects an informer listed when the watch started
+// apart from the ones added afterwards. The informer reports the listed
+// objects as added, possibly after it reports being synced, so they are
+// recognised by their resourceVersion once the list has been stored
+type initialList struct {
+	informer cache.SharedIndexInformer
+	mu       sync.Mutex
+	synced   bool
+	versions map[string]string
+}
+
+// wait waits for the informer to store the objects it listed, or for ctx
+// to be done
+func (l *initialList) wait(ctx context.Context) {
+	if !cache.WaitForCacheSync(ctx.Done(), l.informer.HasSynced) {
+		return
+	}
+	versions := map[string]string{}
+	for _, obj := range l.informer.GetStore().List() {
+		if o, ok := obj.(*unstructured.Unstructured); ok {
+			versions[o.GetName()] = o.GetResourceVersion()
+		}
+	}
+	l.mu.Lock()
+	l.synced, l.versions = true, versions
+	l.mu.Unlock()
+}
+
+// listed reports whether an added object was listed when the watch
+// started. Objects added before the list was stored cannot be told apart
+// from the listed ones, they are treated as listed
+func (l *initialList) listed(o *unstructured.Unstructured) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.synced {
+		return true
+	}
+	version, ok := l.versions[o.GetName()]
+	return ok && version == o.GetResourceVersion()
+}
+
+// handler reports the changes to the selected objects of a kind. Objects
+// in initial are not reported as added
+func handler(gk schema.GroupKind, selection *selection, initial *initialList, changes *Changes) cache.ResourceEventHandler {
+	object := func(obj interface{}) *unstructured.Unstructured {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		o, _ := obj.(*unstructured.Unstructured)
+		return o
+	}
+	selected := func(o *unstructured.Unstructured) bool {
+		return o != nil && selection.selects(gk, o.GetName(), o.GetLabels())
+	}
+	report := func(o *unstructured.Unstructured) {
+		changes.Add(gk.Kind + "/" + o.GetName())
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if o := object(obj); selected(o) && (initial == nil || !initial.listed(o)) {
+				report(o)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// An object that stops being selected leaves the export
+			oldO, newO := object(oldObj), object(newObj)
+			if (selected(oldO) || selected(newO)) && hash(oldObj) != hash(newObj) {
+				report(newO)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if o := object(obj); selected(o) {
+				report(o)
+			}
+		},
+	}
+}
+
+// hash identifies the content of an object that is exported, leaving out
+// the status and the metadata updated by the cluster on every write
+func hash(obj interface{}) string {
+	o, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return ""
+	}
+	content := o.DeepCopy().UnstructuredContent()
+	delete(content, "status")
+	if meta, ok := content["metadata"].(map[string]interface{}); ok {
+		delete(meta, "resourceVersion")
+		delete(meta, "managedFields")
+		delete(meta, "generation")
+	}
+	data, _ := json.Marshal(content)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
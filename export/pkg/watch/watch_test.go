@@ -0,0 +1,308 @@
+package watch
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+var (
+	configMaps  = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	pods        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	deployments = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+)
+
+// resources is the discovery of a cluster serving ConfigMaps, Pods and
+// Deployments
+type resources struct{}
+
+func (resources) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	verbs := metav1.Verbs{"create", "delete", "get", "list", "patch", "update", "watch"}
+	return []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: verbs},
+			{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: verbs},
+			{Name: "bindings", Namespaced: true, Kind: "Binding", Verbs: metav1.Verbs{"create"}},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Namespaced: true, Kind: "Deployment", Verbs: verbs},
+		}},
+	}, nil
+}
+
+func object(gvr schema.GroupVersionResource, kind, name string, labels map[string]string) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	o.SetAPIVersion(gvr.GroupVersion().String())
+	o.SetKind(kind)
+	o.SetNamespace("demo")
+	o.SetName(name)
+	o.SetLabels(labels)
+	return o
+}
+
+// cluster is a namespace followed by a watch
+type cluster struct {
+	t       *testing.T
+	client  *fake.FakeDynamicClient
+	changes *Changes
+}
+
+// startWatch watches the demo namespace, holding objs, and returns once the
+// existing objects have been listed and the resources are watched
+func startWatch(t *testing.T, opts Options, objs ...runtime.Object) *cluster {
+	t.Helper()
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMaps:  "ConfigMapList",
+		pods:        "PodList",
+		deployments: "DeploymentList",
+	}, objs...)
+	c := &cluster{t: t, client: client, changes: NewChanges()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	synced := make(chan struct{})
+	done := make(chan error, 1)
+	opts.Namespace = "demo"
+	go func() {
+		done <- watch(ctx, resources{}, client, opts, c.changes, func() { close(synced) })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	select {
+	case <-synced:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not sync")
+	}
+	// Changes are only seen by the fake client once the watches are open
+	deadline := time.Now().Add(5 * time.Second)
+	for c.watches() < c.resources(opts) {
+		if time.Now().After(deadline) {
+			t.Fatal("resources are not watched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return c
+}
+
+func (c *cluster) watches() int {
+	n := 0
+	for _, action := range c.client.Actions() {
+		if action.GetVerb() == "watch" {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *cluster) resources(opts Options) int {
+	selection, err := newSelection(opts)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	discovered, _ := discover(resources{}, selection)
+	return len(discovered)
+}
+
+func (c *cluster) create(gvr schema.GroupVersionResource, o *unstructured.Unstructured) {
+	c.t.Helper()
+	if _, err := c.client.Resource(gvr).Namespace("demo").Create(context.TODO(), o, metav1.CreateOptions{}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *cluster) update(gvr schema.GroupVersionResource, name string, change func(o *unstructured.Unstructured)) {
+	c.t.Helper()
+	o, err := c.client.Resource(gvr).Namespace("demo").Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	change(o)
+	if _, err := c.client.Resource(gvr).Namespace("demo").Update(context.TODO(), o, metav1.UpdateOptions{}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *cluster) delete(gvr schema.GroupVersionResource, name string) {
+	c.t.Helper()
+	if err := c.client.Resource(gvr).Namespace("demo").Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect waits for the objects reported to be want, and for nothing else
+// to be reported after them
+func (c *cluster) expect(want ...string) {
+	c.t.Helper()
+	if want == nil {
+		want = []string{}
+	}
+	got := map[string]bool{}
+	deadline := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case <-c.changes.Ready():
+			for _, object := range c.changes.Take() {
+				got[object] = true
+			}
+		case <-deadline:
+			c.t.Fatalf("reported %v, want %v", keys(got), want)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, object := range c.changes.Take() {
+		got[object] = true
+	}
+	if !reflect.DeepEqual(keys(got), want) {
+		c.t.Errorf("reported %v, want %v", keys(got), want)
+	}
+}
+
+func keys(set map[string]bool) []string {
+	list := []string{}
+	for key := range set {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestWatch(t *testing.T) {
+	existing := object(configMaps, "ConfigMap", "existing", nil)
+	web := object(deployments, "Deployment", "web", nil)
+	c := startWatch(t, Options{}, existing, web, object(pods, "Pod", "web-1", nil))
+
+	// Listed when the watch started, so exported already
+	c.expect()
+
+	c.create(configMaps, object(configMaps, "ConfigMap", "added", nil))
+	c.create(pods, object(pods, "Pod", "web-2", nil))
+	c.update(configMaps, "existing", func(o *unstructured.Unstructured) {
+		unstructured.SetNestedField(o.Object, "value", "data", "key")
+	})
+	c.update(deployments, "web", func(o *unstructured.Unstructured) {
+		unstructured.SetNestedField(o.Object, int64(2), "status", "replicas")
+	})
+	c.expect("ConfigMap/added", "ConfigMap/existing")
+
+	c.delete(deployments, "web")
+	c.expect("Deployment/web")
+}
+
+func TestWatchSelection(t *testing.T) {
+	labels := map[string]string{"app": "web"}
+	c := startWatch(t, Options{Include: []string{"ConfigMap"}, LabelSelector: "app=web"},
+		object(configMaps, "ConfigMap", "selected", labels),
+		object(configMaps, "ConfigMap", "other", nil))
+	if n := c.resources(Options{Include: []string{"ConfigMap"}}); n != 1 {
+		t.Errorf("watching %d resources, want only ConfigMaps", n)
+	}
+
+	c.create(configMaps, object(configMaps, "ConfigMap", "added", labels))
+	c.create(configMaps, object(configMaps, "ConfigMap", "unlabeled", nil))
+	c.update(configMaps, "other", func(o *unstructured.Unstructured) {
+		unstructured.SetNestedField(o.Object, "value", "data", "key")
+	})
+	c.expect("ConfigMap/added")
+
+	// Leaving the selection removes the object from the export
+	c.update(configMaps, "selected", func(o *unstructured.Unstructured) {
+		o.SetLabels(nil)
+	})
+	c.expect("ConfigMap/selected")
+}
+
+func TestHandlerInitialList(t *testing.T) {
+	selection, err := newSelection(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := func(initial *initialList, name, version string) []string {
+		changes := NewChanges()
+		o := object(configMaps, "ConfigMap", name, nil)
+		o.SetResourceVersion(version)
+		handler(schema.GroupKind{Kind: "ConfigMap"}, selection, initial, changes).OnAdd(o)
+		return changes.Take()
+	}
+
+	listing := &initialList{}
+	if got := added(listing, "existing", "1"); len(got) != 0 {
+		t.Errorf("reported %v before the list was stored", got)
+	}
+
+	// The informer may report the listed objects after it has synced
+	listed := &initialList{synced: true, versions: map[string]string{"existing": "1"}}
+	if got := added(listed, "existing", "1"); len(got) != 0 {
+		t.Errorf("reported listed object %v", got)
+	}
+	if got, want := added(listed, "existing", "2"), []string{"ConfigMap/existing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recreated object reported as %v, want %v", got, want)
+	}
+	if got, want := added(listed, "added", "3"), []string{"ConfigMap/added"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("added object reported as %v, want %v", got, want)
+	}
+
+	// Resources discovered later report every object
+	if got, want := added(nil, "existing", "1"), []string{"ConfigMap/existing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("object of a new resource reported as %v, want %v", got, want)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	tests := map[string]struct {
+		opts     Options
+		kind     string
+		name     string
+		labels   map[string]string
+		watched  bool
+		selected bool
+	}{
+		"everything":              {kind: "ConfigMap", watched: true, selected: true},
+		"ignored":                 {kind: "Pod"},
+		"excluded":                {opts: Options{Exclude: []string{"Deployment.apps"}}, kind: "Deployment.apps"},
+		"included":                {opts: Options{Include: []string{"Deployment.apps"}}, kind: "Deployment.apps", watched: true, selected: true},
+		"not included":            {opts: Options{Include: []string{"Deployment.apps"}}, kind: "ConfigMap"},
+		"excluded and included":   {opts: Options{Include: []string{"ConfigMap"}, Exclude: []string{"ConfigMap"}}, kind: "ConfigMap"},
+		"matching labels":         {opts: Options{LabelSelector: "app=web"}, kind: "ConfigMap", labels: web, watched: true, selected: true},
+		"other labels":            {opts: Options{LabelSelector: "app=web"}, kind: "ConfigMap", watched: true},
+		"named object":            {opts: Options{Objects: []string{"ConfigMap/settings"}}, kind: "ConfigMap", name: "settings", watched: true, selected: true},
+		"other object":            {opts: Options{Objects: []string{"ConfigMap/settings"}}, kind: "ConfigMap", name: "flags", watched: true},
+		"kind of no named object": {opts: Options{Objects: []string{"ConfigMap/settings"}}, kind: "Service"},
+		"named object not matching": {
+			opts:    Options{Objects: []string{"ConfigMap/settings"}, LabelSelector: "app=web"},
+			kind:    "ConfigMap",
+			name:    "settings",
+			watched: true, selected: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := newSelection(test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gk := schema.ParseGroupKind(test.kind)
+			if got := s.watchesKind(gk); got != test.watched {
+				t.Errorf("watchesKind = %v, want %v", got, test.watched)
+			}
+			if got := s.selects(gk, test.name, test.labels); got != test.selected {
+				t.Errorf("selects = %v, want %v", got, test.selected)
+			}
+		})
+	}
+
+	if _, err := newSelection(Options{LabelSelector: "app in (web"}); err == nil {
+		t.Error("invalid label selector accepted")
+	}
+}
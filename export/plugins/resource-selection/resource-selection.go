@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/konveyor/crane-lib/transform"
+	"github.com/konveyor/crane-lib/transform/cli"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The selection is handed to the plugin by the export job through the
+// environment, crane passes its environment on to every plugin it runs
+var includedKinds = splitList(os.Getenv("INCLUDED_KINDS"))
+var excludedKinds = splitList(os.Getenv("EXCLUDED_KINDS"))
+var labelSelector = os.Getenv("LABEL_SELECTOR")
+var objects = splitList(os.Getenv("OBJECTS"))
+
+func main() {
+	cli.RunAndExit(cli.NewCustomPlugin("ResourceSelectionPlugin", "v1", nil, Run))
+}
+
+func Run(u *unstructured.Unstructured, extras map[string]string) (transform.PluginResponse, error) {
+	var patch jsonpatch.Patch
+	selected, err := IsSelected(*u)
+	if err != nil {
+		return transform.PluginResponse{}, err
+	}
+	return transform.PluginResponse{
+		Version:    "v1",
+		IsWhiteOut: !selected,
+		Patches:    patch,
+	}, nil
+}
+
+// IsSelected reports whether the object should be kept in the export.
+// Excluded kinds always win, named objects are always kept and
+// everything else has to match both the included kinds and the label
+// selector when they are set
+func IsSelected(u unstructured.Unstructured) (bool, error) {
+	gk := u.GroupVersionKind().GroupKind()
+	if containsKind(excludedKinds, gk) {
+		return false, nil
+	}
+	if isNamedObject(gk, u.GetName()) {
+		return true, nil
+	}
+	if len(includedKinds) == 0 && labelSelector == "" {
+		// Only named objects were asked for
+		return len(objects) == 0, nil
+	}
+	if len(includedKinds) > 0 && !containsKind(includedKinds, gk) {
+		return false, nil
+	}
+	if labelSelector != "" {
+		selector, err := labels.Parse(labelSelector)
+		if err != nil {
+			return false, err
+		}
+		return selector.Matches(labels.Set(u.GetLabels())), nil
+	}
+	return true, nil
+}
+
+func containsKind(kinds []string, gk schema.GroupKind) bool {
+	for _, k := range kinds {
+		if schema.ParseGroupKind(k) == gk {
+			return true
+		}
+	}
+	return false
+}
+
+func isNamedObject(gk schema.GroupKind, name string) bool {
+	for _, o := range objects {
+		i := strings.LastIndex(o, "/")
+		if i == -1 {
+			continue
+		}
+		if schema.ParseGroupKind(o[:i]) == gk && o[i+1:] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -9,10 +9,13 @@ require (
 	github.com/onsi/gomega v1.13.0
 	github.com/openshift/api v0.0.0-20210625082935-ad54d363d274
 	github.com/operator-framework/operator-lib v0.1.0
+	github.com/prometheus/client_golang v1.11.0
 	github.com/sethvargo/go-password v0.2.0
 	github.com/sirupsen/logrus v1.8.1
+	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
 	k8s.io/api v0.21.2
 	k8s.io/apimachinery v0.21.3
 	k8s.io/client-go v0.21.2
 	sigs.k8s.io/controller-runtime v0.9.2
+	sigs.k8s.io/yaml v1.2.0
 )
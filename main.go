@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -29,6 +30,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
@@ -53,11 +55,14 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var sweepInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&sweepInterval, "sweep-interval", 10*time.Minute,
+		"How often to delete cluster scoped objects left behind by deleted Exports.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -73,6 +78,7 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "86f835c3.example.com",
+		NewCache:               cache.BuilderWithOptions(cache.Options{SelectorsByObject: controllers.CacheSelectors()}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -86,6 +92,13 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Export")
 		os.Exit(1)
 	}
+	if err = mgr.Add(&controllers.ClusterObjectSweeper{
+		Client:   mgr.GetClient(),
+		Interval: sweepInterval,
+	}); err != nil {
+		setupLog.Error(err, "unable to create sweeper")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
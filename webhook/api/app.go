@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
 
 	primerv1alpha1 "github.com/cooktheryan/gitops-primer/api/v1alpha1"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -37,34 +38,57 @@ func (app *App) HandleMutate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userName, err := json.Marshal(&ar.Username)
-	if err != nil {
-		app.HandleError(w, r, fmt.Errorf("marshall user: %v", err))
+	// build admission response
+	admissionResponse := &admissionv1.AdmissionResponse{
+		UID:     admissionReview.Request.UID,
+		Allowed: true,
 	}
 
-	// build json patch
-	patch := []JSONPatchEntry{
-		JSONPatchEntry{
-			OP:    "add",
-			Path:  "/spec/user",
-			Value: userName,
-		},
+	// updates that leave the whole spec alone, such as the controller
+	// adding its finalizer, keep the user the export runs as. Any other
+	// change, including to the user itself, runs the export as the
+	// requester. The specs are compared as JSON so that no field is
+	// missed when the Export type vendored here is older than the CRD
+	specChanged := true
+	if admissionReview.Request.Operation == admissionv1.Update {
+		oldSpec, err := rawSpec(admissionReview.Request.OldObject.Raw)
+		if err != nil {
+			app.HandleError(w, r, fmt.Errorf("unmarshal to old export: %v", err))
+			return
+		}
+		newSpec, err := rawSpec(admissionReview.Request.Object.Raw)
+		if err != nil {
+			app.HandleError(w, r, fmt.Errorf("unmarshal to export: %v", err))
+			return
+		}
+		specChanged = !reflect.DeepEqual(oldSpec, newSpec)
 	}
 
-	patchBytes, err := json.Marshal(&patch)
-	if err != nil {
-		app.HandleError(w, r, fmt.Errorf("marshall jsonpatch: %v", err))
-		return
-	}
+	if specChanged {
+		userName, err := json.Marshal(&ar.Username)
+		if err != nil {
+			app.HandleError(w, r, fmt.Errorf("marshall user: %v", err))
+			return
+		}
 
-	patchType := admissionv1.PatchTypeJSONPatch
+		// build json patch
+		patch := []JSONPatchEntry{
+			JSONPatchEntry{
+				OP:    "add",
+				Path:  "/spec/user",
+				Value: userName,
+			},
+		}
 
-	// build admission response
-	admissionResponse := &admissionv1.AdmissionResponse{
-		UID:       admissionReview.Request.UID,
-		Allowed:   true,
-		Patch:     patchBytes,
-		PatchType: &patchType,
+		patchBytes, err := json.Marshal(&patch)
+		if err != nil {
+			app.HandleError(w, r, fmt.Errorf("marshall jsonpatch: %v", err))
+			return
+		}
+
+		patchType := admissionv1.PatchTypeJSONPatch
+		admissionResponse.Patch = patchBytes
+		admissionResponse.PatchType = &patchType
 	}
 
 	respAdmissionReview := &admissionv1.AdmissionReview{
@@ -78,6 +102,17 @@ func (app *App) HandleMutate(w http.ResponseWriter, r *http.Request) {
 	jsonOk(w, &respAdmissionReview)
 }
 
+// rawSpec returns the spec of the Export in raw as generic JSON
+func rawSpec(raw []byte) (map[string]interface{}, error) {
+	object := struct {
+		Spec map[string]interface{} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, err
+	}
+	return object.Spec, nil
+}
+
 type JSONPatchEntry struct {
 	OP    string          `json:"op"`
 	Path  string          `json:"path"`
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const export = `{"apiVersion":"primer.gitops.io/v1alpha1","kind":"Export","metadata":{"name":"demo","namespace":"demo"%s},"spec":{"method":"git","repo":"git@example.com:org/gitops.git","branch":"main"%s}}`
+
+// mutate sends the admission request of an operation by alice on an
+// Export and returns the patch of the response, nil when there is none
+func mutate(t *testing.T, operation admissionv1.Operation, object, oldObject string) []JSONPatchEntry {
+	t.Helper()
+	review := admissionv1.AdmissionReview{Request: &admissionv1.AdmissionRequest{
+		UID:       "1234",
+		Operation: operation,
+		UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+		Object:    runtime.RawExtension{Raw: []byte(object)},
+	}}
+	if oldObject != "" {
+		review.Request.OldObject = runtime.RawExtension{Raw: []byte(oldObject)}
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	(&App{}).HandleMutate(w, httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	response := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !response.Response.Allowed || response.Response.UID != "1234" {
+		t.Fatalf("response = %+v", response.Response)
+	}
+	if response.Response.Patch == nil {
+		return nil
+	}
+	patch := []JSONPatchEntry{}
+	if err := json.Unmarshal(response.Response.Patch, &patch); err != nil {
+		t.Fatal(err)
+	}
+	return patch
+}
+
+// runsAsAlice reports whether patch sets the user of the Export to alice
+func runsAsAlice(patch []JSONPatchEntry) bool {
+	return len(patch) == 1 && patch[0].OP == "add" && patch[0].Path == "/spec/user" && string(patch[0].Value) == `"alice"`
+}
+
+func TestHandleMutate(t *testing.T) {
+	tests := map[string]struct {
+		operation admissionv1.Operation
+		object    string
+		oldObject string
+		runAs     bool
+	}{
+		"create": {
+			operation: admissionv1.Create,
+			object:    fmt.Sprintf(export, "", ""),
+			runAs:     true,
+		},
+		"create as another user": {
+			operation: admissionv1.Create,
+			object:    fmt.Sprintf(export, "", `,"user":"bob"`),
+			runAs:     true,
+		},
+		"finalizer added": {
+			operation: admissionv1.Update,
+			object:    fmt.Sprintf(export, `,"finalizers":["primer.gitops.io/finalizer"]`, `,"user":"bob"`),
+			oldObject: fmt.Sprintf(export, "", `,"user":"bob"`),
+		},
+		"spec changed": {
+			operation: admissionv1.Update,
+			object:    fmt.Sprintf(export, "", `,"user":"bob","path":"clusters/demo"`),
+			oldObject: fmt.Sprintf(export, "", `,"user":"bob"`),
+			runAs:     true,
+		},
+		// Fields newer than the Export type vendored by the webhook
+		"unknown spec field changed": {
+			operation: admissionv1.Update,
+			object:    fmt.Sprintf(export, "", `,"user":"bob","futureField":{"enabled":true}`),
+			oldObject: fmt.Sprintf(export, "", `,"user":"bob"`),
+			runAs:     true,
+		},
+		"user changed": {
+			operation: admissionv1.Update,
+			object:    fmt.Sprintf(export, "", `,"user":"alice"`),
+			oldObject: fmt.Sprintf(export, "", `,"user":"bob"`),
+			runAs:     true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			patch := mutate(t, test.operation, test.object, test.oldObject)
+			if test.runAs && !runsAsAlice(patch) {
+				t.Errorf("patch = %+v, want the user set to alice", patch)
+			}
+			if !test.runAs && patch != nil {
+				t.Errorf("patch = %+v, want none", patch)
+			}
+		})
+	}
+}
+
+func TestHandleMutateInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&App{}).HandleMutate(w, httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader([]byte("{"))))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}